@@ -3,58 +3,195 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/berberapan/info-eval/internal/data"
-	"github.com/google/generative-ai-go/genai"
+	"github.com/berberapan/info-eval/internal/feedback"
+	"github.com/berberapan/info-eval/internal/grading"
+	"github.com/berberapan/info-eval/internal/promptguard"
+	"github.com/berberapan/info-eval/internal/redact"
 	"github.com/google/uuid"
-	"google.golang.org/api/option"
 )
 
-func (app *application) triggerAIFeedbackGeneration(responseID uuid.UUID, scenarioSessionID uuid.UUID, rawAnswersJSON []byte) {
-	app.wg.Add(1)
-	go func() {
-		defer app.wg.Done()
-		err := app.generateAndStoreAIFeedback(responseID, scenarioSessionID, rawAnswersJSON)
-		if err != nil {
-			app.logger.Error("Failed to generate or store AI feedback", "response_id", responseID.String(), "scenario_session_id", scenarioSessionID.String(), "error", err)
-		}
-	}()
-}
-
-func (app *application) generateAndStoreAIFeedback(responseID uuid.UUID, scenarioSessionID uuid.UUID, rawAnswersJSON []byte) error {
+func (app *application) generateAndStoreAIFeedback(ctx context.Context, responseID uuid.UUID) error {
+	response, err := app.models.SessionResponses.Get(responseID)
+	if err != nil {
+		return fmt.Errorf("failed to get session response %s: %w", responseID, err)
+	}
+	scenarioSessionID := response.ScenarioSessionID
 	var studentAnswers map[string]any
-	if err := json.Unmarshal(rawAnswersJSON, &studentAnswers); err != nil {
+	if err := json.Unmarshal(response.RawAnswers, &studentAnswers); err != nil {
 		return fmt.Errorf("failed to unmarshal raw_answers for response %s: %w", responseID, err)
 	}
-	scenarioID, err := app.models.ScenarioSessions.GetScenarioByID(scenarioSessionID)
+	session, err := app.models.ScenarioSessions.Get(scenarioSessionID)
 	if err != nil {
 		if err == data.ErrRecordNotFound {
 			return fmt.Errorf("scenario_session_id %s not found when trying to get scenario_id: %w", scenarioSessionID, err)
 		}
-		return fmt.Errorf("failed to get scenario_id for scenario_session_id %s: %w", scenarioSessionID, err)
+		return fmt.Errorf("failed to get scenario_session_id %s: %w", scenarioSessionID, err)
 	}
-	questions, err := app.models.ExerciseQuestions.GetAllByScenarioIDAsMap(scenarioID)
+	scenario, err := app.sessionScenario(session)
 	if err != nil {
-		return fmt.Errorf("failed to fetch questions for scenario %s: %w", scenarioID, err)
+		return fmt.Errorf("failed to fetch questions for scenario %s: %w", session.ScenarioID, err)
+	}
+	templates, err := app.activePromptTemplates()
+	if err != nil {
+		return fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+	states, err := app.models.ResponseFeedback.GetByResponseID(responseID)
+	if err != nil {
+		return fmt.Errorf("failed to get feedback status for response %s: %w", responseID, err)
+	}
+	pendingAnswers := make(map[string]any)
+	aiFeedbackResults := make(map[string]string)
+	for _, state := range states {
+		switch state.Status {
+		case data.FeedbackPending:
+			pendingAnswers[state.QuestionID.String()] = studentAnswers[state.QuestionID.String()]
+		case data.FeedbackDone:
+			aiFeedbackResults[state.QuestionID.String()] = state.Feedback
+		}
+	}
+	var firstErr error
+	for qIDStr, result := range app.generateFeedback(ctx, responseID, session.Language.OrDefault(), templates, scenario.QuestionMap(), pendingAnswers) {
+		questionID := uuid.MustParse(qIDStr)
+		if len(result.flags) > 0 {
+			app.logger.Warn("Answer flagged for teacher review", "response_id", responseID.String(), "question_id", qIDStr, "flags", result.flags)
+			if err := app.models.ResponseFeedback.Flag(responseID, questionID, result.flags); err != nil {
+				return fmt.Errorf("failed to flag answer for response %s: %w", responseID, err)
+			}
+		}
+		switch {
+		case result.permanent:
+			app.logger.Warn("Feedback can't be generated for answer", "response_id", responseID.String(), "question_id", qIDStr, "error", result.err)
+			err = app.models.ResponseFeedback.MarkFailed(responseID, questionID, result.err.Error())
+		case result.err != nil:
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			err = app.models.ResponseFeedback.SetError(responseID, questionID, result.err.Error())
+		default:
+			aiFeedbackResults[qIDStr] = result.text
+			err = app.models.ResponseFeedback.MarkDone(responseID, questionID, result.text, result.assessment, result.promptTemplateID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store feedback status for response %s: %w", responseID, err)
+		}
+	}
+	if len(aiFeedbackResults) > 0 {
+		err = app.models.SessionResponses.AddFeedback(responseID, aiFeedbackResults)
+		if err != nil {
+			return fmt.Errorf("failed to store AI feedback for response %s: %w", responseID, err)
+		}
+	}
+	if firstErr != nil || response.AISummary != nil {
+		return firstErr
+	}
+	template := templates[data.PromptSummary]
+	summary, err := app.summarizeResponse(ctx, responseID, session.Language.OrDefault(), template, scenario, studentAnswers, aiFeedbackResults)
+	if err != nil || summary == nil {
+		return err
+	}
+	if err := app.models.SessionResponses.SetSummary(responseID, summary, feedback.PromptTemplateID(template)); err != nil {
+		return fmt.Errorf("failed to store summary for response %s: %w", responseID, err)
+	}
+	return nil
+}
+
+// summarizeResponse is the second pass over a response, once every answer has
+// its own feedback. Closed questions are described by their grading so the
+// model sees the whole exercise, not just the free-text answers.
+func (app *application) summarizeResponse(ctx context.Context, responseID uuid.UUID, language data.Language, template *data.PromptTemplate, scenario *data.Scenario, studentAnswers map[string]any, aiFeedback map[string]string) (*data.FeedbackSummary, error) {
+	var answers []data.PromptAnswer
+	for _, e := range scenario.Exercises {
+		for _, q := range e.Questions {
+			answer, ok := studentAnswers[q.ID.String()]
+			if !ok {
+				continue
+			}
+			note := aiFeedback[q.ID.String()]
+			if result, ok := grading.Grade(&q, answer); ok {
+				note = gradingNote(result)
+			}
+			answers = append(answers, feedback.SummaryAnswer(q.Question, grading.Describe(&q, answer), note))
+		}
 	}
-	ctx := context.Background()
-	if app.config.ai.key == "" {
-		app.logger.Warn("Gemini API key is not configured. Skipping AI feedback generation.", "response_id", responseID.String())
-		return nil
+	if len(answers) == 0 {
+		return nil, nil
 	}
-	client, err := genai.NewClient(ctx, option.WithAPIKey(app.config.ai.key))
+	generator := app.feedbackGenerator(app.logger.With("response_id", responseID.String()), uuid.NullUUID{UUID: responseID, Valid: true}, uuid.NullUUID{})
+	summary, err := generator.Summarize(ctx, template, language, answers)
 	if err != nil {
-		return fmt.Errorf("failed to create Gemini client for response %s: %w", responseID, err)
+		app.logger.Error("Failed to summarize response", "provider", app.llm.Name(), "response_id", responseID.String(), "error", err)
+		return nil, fmt.Errorf("failed to summarize response %s: %w", responseID, err)
 	}
-	defer client.Close()
+	return summary, nil
+}
 
-	model := client.GenerativeModel("gemini-1.5-flash-latest")
-	model.SetTemperature(0.1)
+func gradingNote(result grading.Result) string {
+	var note string
+	switch {
+	case result.Correct:
+		note = "Correct"
+	case result.Score > 0:
+		note = fmt.Sprintf("Partly correct (%.0f%%)", 100*result.Score/result.MaxScore)
+	default:
+		note = "Incorrect"
+	}
+	if len(result.Feedback) > 0 {
+		note += ". " + strings.Join(result.Feedback, " ")
+	}
+	return note
+}
 
-	aiFeedbackResults := make(map[string]string)
+type questionFeedback struct {
+	text             string
+	assessment       *data.Assessment
+	promptTemplateID uuid.NullUUID
+	flags            []string
+	err              error
+	// permanent is set when err comes from the answer or the question
+	// itself, so retrying the job won't help.
+	permanent bool
+}
+
+// restored puts the student's personal data back into feedback that was
+// generated or cached with placeholders.
+func (f questionFeedback) restored(redactor *redact.Redactor) questionFeedback {
+	if f.err != nil {
+		return f
+	}
+	result := feedback.Result{Text: f.text, Assessment: f.assessment}
+	result.Restore(redactor)
+	f.text, f.assessment = result.Text, result.Assessment
+	return f
+}
+
+func (app *application) activePromptTemplates() (map[data.PromptKind]*data.PromptTemplate, error) {
+	templates := make(map[data.PromptKind]*data.PromptTemplate)
+	for _, kind := range data.PromptKinds {
+		template, err := app.models.PromptTemplates.GetLatest(kind)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				return nil, err
+			}
+			template = data.DefaultPromptTemplates[kind]
+		}
+		templates[kind] = template
+	}
+	return templates, nil
+}
+
+// generateFeedback asks the model about every free-text answer in parallel.
+// The provider's limiter decides how many of those calls actually run at once.
+func (app *application) generateFeedback(ctx context.Context, responseID uuid.UUID, language data.Language, templates map[data.PromptKind]*data.PromptTemplate, questions map[uuid.UUID]data.ExerciseQuestion, studentAnswers map[string]any) map[string]questionFeedback {
+	results := make(map[string]questionFeedback)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
 	for qIDStr, studentAnswerInterface := range studentAnswers {
 		questionID, err := uuid.Parse(qIDStr)
@@ -65,81 +202,102 @@ func (app *application) generateAndStoreAIFeedback(responseID uuid.UUID, scenari
 		questionDetails, ok := questions[questionID]
 		if !ok {
 			app.logger.Warn("Question details not found for ID in raw_answers", "question_id", questionID.String(), "response_id", responseID.String())
+			mu.Lock()
+			results[qIDStr] = questionFeedback{err: errors.New("question is no longer part of the scenario"), permanent: true}
+			mu.Unlock()
 			continue
 		}
-		if questionDetails.ExerciseType == data.FreeTextType {
-			studentAnswer, ok := studentAnswerInterface.(string)
-			if !ok {
-				app.logger.Warn("Free-text answer is not a string", "question_id", questionID.String(), "response_id", responseID.String(), "answer_type", fmt.Sprintf("%T", studentAnswerInterface))
-				continue
-			}
-			var promptBuilder strings.Builder
-			promptBuilder.WriteString(fmt.Sprintf("The student was asked the following question: \"%s\"\n", questionDetails.Question))
-			if questionDetails.PromptGuidance.Valid && questionDetails.PromptGuidance.String != "" {
-				promptBuilder.WriteString(fmt.Sprintf("Consider the following guidance when evaluating the answer: \"%s\"\n", questionDetails.PromptGuidance.String))
-			}
-			promptBuilder.WriteString(fmt.Sprintf("The student's answer was: \"%s\"\n\n", studentAnswer))
-			promptBuilder.WriteString("You're an expert on information evaluation and sources. Provide concise, constructive feedback on the student's answer (1-3 sentences). Focus on clarity, accuracy, and completeness. The feedback should be encouraging and help the student understand how to improve or what they did well. The feedback should also be in Swedish")
-			prompt := promptBuilder.String()
+		if questionDetails.ExerciseType != data.FreeTextType {
+			continue
+		}
+		studentAnswer, ok := studentAnswerInterface.(string)
+		if !ok {
+			app.logger.Warn("Free-text answer is not a string", "question_id", questionID.String(), "response_id", responseID.String(), "answer_type", fmt.Sprintf("%T", studentAnswerInterface))
+			mu.Lock()
+			results[qIDStr] = questionFeedback{err: errors.New("answer is not text"), permanent: true}
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := app.questionFeedback(ctx, responseID, language, templates, questionDetails, studentAnswer)
+			result.flags = promptguard.Inspect(studentAnswer)
+			mu.Lock()
+			results[qIDStr] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (app *application) questionFeedback(ctx context.Context, responseID uuid.UUID, language data.Language, templates map[data.PromptKind]*data.PromptTemplate, question data.ExerciseQuestion, studentAnswer string) questionFeedback {
+	template := templates[data.PromptFreeText]
+	if question.Rubric != nil {
+		template = templates[data.PromptRubric]
+	}
+	// The cache only ever sees the redacted answer and feedback with
+	// placeholders; the student's own details are put back on the way out.
+	redactor := redact.New()
+	redactedAnswer := redactor.Redact(studentAnswer)
+	if app.config.feedback.cacheTTL <= 0 {
+		return app.modelFeedback(ctx, responseID, template, language, question, redactor, redactedAnswer).restored(redactor)
+	}
 
-			apiCtx, cancelAPICall := context.WithTimeout(ctx, 60*time.Second)
+	key := data.FeedbackCacheKey(app.llm.Name(), template, language, &question, redactedAnswer)
+	cached, err := app.models.FeedbackCache.Get(key)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.logger.Error("Failed to read feedback cache", "response_id", responseID.String(), "question_id", question.ID.String(), "error", err)
+	}
+	if err := app.models.FeedbackCache.RecordLookup(cached != nil); err != nil {
+		app.logger.Error("Failed to record feedback cache lookup", "error", err)
+	}
+	if cached != nil {
+		return questionFeedback{text: cached.Feedback, assessment: cached.Assessment, promptTemplateID: cached.PromptTemplateID}.restored(redactor)
+	}
 
-			var geminiResp *genai.GenerateContentResponse
-			var genErr error
-			maxRetries := 2
-			for i := 0; i < maxRetries; i++ {
-				geminiResp, genErr = model.GenerateContent(apiCtx, genai.Text(prompt))
-				if genErr == nil {
-					break
-				}
-				app.logger.Error("Error generating feedback from Gemini", "attempt", i+1, "max_attempts", maxRetries, "response_id", responseID.String(), "question_id", questionID.String(), "error", genErr)
-				if i < maxRetries-1 {
-					select {
-					case <-time.After(time.Duration(i+1) * 2 * time.Second):
-					case <-apiCtx.Done():
-						genErr = apiCtx.Err()
-						break
-					}
-				}
-				if genErr != nil && strings.Contains(genErr.Error(), "context deadline exceeded") {
-					break
-				}
-			}
-			cancelAPICall()
-			if genErr != nil {
-				app.logger.Error("Failed to generate feedback after retries", "response_id", responseID.String(), "question_id", questionID.String(), "error", genErr)
-				aiFeedbackResults[qIDStr] = "Error: Could not generate feedback at this time."
-				continue
-			}
-			feedbackText := extractTextFromGeminiResponse(geminiResp)
-			if feedbackText == "" {
-				aiFeedbackResults[qIDStr] = "No specific feedback generated."
-			} else {
-				aiFeedbackResults[qIDStr] = feedbackText
-			}
+	result := app.modelFeedback(ctx, responseID, template, language, question, redactor, redactedAnswer)
+	if result.err == nil {
+		cached = &data.CachedFeedback{Feedback: result.text, Assessment: result.assessment, PromptTemplateID: result.promptTemplateID}
+		if err := app.models.FeedbackCache.Set(key, app.llm.Name(), cached, app.config.feedback.cacheTTL); err != nil {
+			app.logger.Error("Failed to store feedback in cache", "response_id", responseID.String(), "question_id", question.ID.String(), "error", err)
 		}
 	}
-	if len(aiFeedbackResults) > 0 {
-		err = app.models.SessionResponses.AddFeedback(responseID, aiFeedbackResults)
-		if err != nil {
-			return fmt.Errorf("failed to store AI feedback for response %s: %w", responseID, err)
-		}
+	return result.restored(redactor)
+}
+
+// modelFeedback asks the model about an answer that has already been
+// redacted. The feedback it returns still has the placeholders in it.
+func (app *application) modelFeedback(ctx context.Context, responseID uuid.UUID, template *data.PromptTemplate, language data.Language, question data.ExerciseQuestion, redactor *redact.Redactor, redactedAnswer string) questionFeedback {
+	generator := app.feedbackGenerator(app.logger.With("response_id", responseID.String()), uuid.NullUUID{UUID: responseID, Valid: true}, uuid.NullUUID{})
+	result, err := generator.GenerateRedacted(ctx, template, language, question, redactor, redactedAnswer)
+	if err != nil {
+		app.logger.Error("Failed to generate feedback", "provider", app.llm.Name(), "response_id", responseID.String(), "question_id", question.ID.String(), "error", err)
+		return questionFeedback{err: err}
 	}
-	return nil
+	return questionFeedback{text: result.Text, assessment: result.Assessment, promptTemplateID: result.PromptTemplateID}
 }
 
-func extractTextFromGeminiResponse(resp *genai.GenerateContentResponse) string {
-	var feedback strings.Builder
-	if resp != nil {
-		for _, cand := range resp.Candidates {
-			if cand.Content != nil {
-				for _, part := range cand.Content.Parts {
-					if textPart, ok := part.(genai.Text); ok {
-						feedback.WriteString(string(textPart))
-					}
+// feedbackGenerator returns a generator that records every redaction against
+// the response or session the student text came from.
+func (app *application) feedbackGenerator(logger *slog.Logger, responseID, sessionID uuid.NullUUID) *feedback.Generator {
+	return &feedback.Generator{
+		LLM:    app.llm,
+		Logger: logger,
+		Redacted: func(what string, questionID uuid.UUID, matches []redact.Match) error {
+			redactions := make([]*data.Redaction, len(matches))
+			for i, m := range matches {
+				redactions[i] = &data.Redaction{
+					Kind:              string(m.Kind),
+					Purpose:           what,
+					SessionResponseID: responseID,
+					ScenarioSessionID: sessionID,
+					QuestionID:        uuid.NullUUID{UUID: questionID, Valid: questionID != uuid.Nil},
+					Provider:          app.llm.Name(),
 				}
 			}
-		}
+			return app.models.Redactions.Insert(redactions)
+		},
 	}
-	return feedback.String()
 }
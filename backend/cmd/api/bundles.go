@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+const bundleTransferTimeout = 5 * time.Minute
+
+func (app *application) exportScenarioBundleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	scenario, err := app.models.Scenarios.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(bundleTransferTimeout))
+
+	bundle := data.NewScenarioBundle(scenario)
+	err = app.models.Bundles.PackFiles(r.Context(), bundle, app.storage)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	js, err := json.MarshalIndent(bundle, "", "\t")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	js = append(js, '\n')
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"scenario-%s.json\"", scenario.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+func (app *application) importScenarioBundleHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(bundleTransferTimeout))
+
+	var bundle data.ScenarioBundle
+	err := app.readJSONLimit(w, r, &bundle, int64(app.config.media.maxBundleMB)<<20)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	data.ValidateScenarioBundle(v, &bundle)
+	data.ValidateBundleFiles(v, bundle.Files, app.mediaLimits())
+	if !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Bundles.CheckUploadedMedia(v, &bundle)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	user := uuid.NullUUID{UUID: app.contextGetUser(r).ID, Valid: true}
+	scenarioID, err := app.models.Bundles.Import(&bundle, user, uuid.NullUUID{}, app.storage)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	scenario, err := app.models.Scenarios.Get(scenarioID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/scenario/%s", scenario.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"scenario": scenario}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) cloneScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Title *string `json:"title"`
+	}
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	source, err := app.models.Scenarios.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Published scenarios can be cloned by every teacher, others only by
+	// those they're shared with.
+	if source.Status != data.ScenarioPublished {
+		permission, err := app.models.ScenarioShares.GetPermission(source.ID, app.contextGetUser(r))
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !permission.Includes(data.PermissionView) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+	bundle := data.NewScenarioBundle(source)
+	if input.Title != nil {
+		bundle.Scenario.Title = *input.Title
+	}
+	v := validator.New()
+	if data.ValidateScenarioBundle(v, bundle); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	owner := uuid.NullUUID{UUID: app.contextGetUser(r).ID, Valid: true}
+	scenarioID, err := app.models.Bundles.Import(bundle, owner, uuid.NullUUID{UUID: source.ID, Valid: true}, app.storage)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	scenario, err := app.models.Scenarios.Get(scenarioID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/scenario/%s", scenario.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"scenario": scenario}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/feedback"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+// A report can take a few model calls when the first replies are rejected, so
+// it gets more time than the server's default write timeout.
+const classReportTimeout = 3 * time.Minute
+
+func (app *application) classReportHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	questionID, err := app.readUUIDParam(r, "question_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	session, err := app.models.ScenarioSessions.Get(sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	permission, err := app.sessionPermission(r, session)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !permission.Includes(data.PermissionView) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	scenario, err := app.sessionScenario(session)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	question, ok := scenario.QuestionMap()[questionID]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+	v := validator.New()
+	if v.Check(question.ExerciseType == data.FreeTextType, "question_id", "must be a free-text question"); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+
+	submitted, lastSubmittedAt, err := app.models.SessionResponses.GetTextAnswers(session.ID, question.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var answers []string
+	for _, answer := range submitted {
+		if strings.TrimSpace(answer) != "" {
+			answers = append(answers, answer)
+		}
+	}
+	stored, err := app.models.ClassReports.Get(session.ID, question.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if stored != nil && stored.Covers(len(answers), lastSubmittedAt) {
+		app.writeClassReport(w, r, stored, true)
+		return
+	}
+	stored = &data.StoredClassReport{
+		ScenarioSessionID: session.ID,
+		QuestionID:        question.ID,
+		Report:            &data.ClassReport{Themes: []data.ClassReportTheme{}, Misconceptions: []data.ClassReportMisconception{}},
+		AnswerCount:       len(answers),
+		LastSubmittedAt:   lastSubmittedAt,
+		CreatedAt:         time.Now(),
+	}
+	if len(answers) == 0 {
+		app.writeClassReport(w, r, stored, false)
+		return
+	}
+
+	if app.llm == nil {
+		app.errorResponse(w, r, http.StatusServiceUnavailable, "no LLM provider is configured")
+		return
+	}
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(classReportTimeout))
+
+	templates, err := app.activePromptTemplates()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	template := templates[data.PromptClassReport]
+	if len(answers) > feedback.MaxReportAnswers {
+		answers = answers[len(answers)-feedback.MaxReportAnswers:]
+	}
+	generator := app.feedbackGenerator(app.logger.With("scenario_session_id", session.ID.String()), uuid.NullUUID{}, uuid.NullUUID{UUID: session.ID, Valid: true})
+	stored.Report, err = generator.ClassReport(r.Context(), template, session.Language.OrDefault(), question, answers)
+	if err != nil {
+		app.logger.Error("Failed to generate class report", "provider", app.llm.Name(), "scenario_session_id", session.ID.String(), "question_id", question.ID.String(), "error", err)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	stored.PromptTemplateID = feedback.PromptTemplateID(template)
+	if err := app.models.ClassReports.Upsert(stored); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.writeClassReport(w, r, stored, false)
+}
+
+func (app *application) writeClassReport(w http.ResponseWriter, r *http.Request, report *data.StoredClassReport, cached bool) {
+	err := app.writeJSON(w, http.StatusOK, jsonEnvelope{"class_report": report, "cached": cached}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+type reorderInput struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+func (app *application) exerciseFromRequest(r *http.Request) (*data.Exercise, error) {
+	scenarioID, err := app.readIDParam(r)
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+	exerciseID, err := app.readUUIDParam(r, "exercise_id")
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+	exercise, err := app.models.Exercises.Get(exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	if exercise.ScenarioID != scenarioID {
+		return nil, data.ErrRecordNotFound
+	}
+	return exercise, nil
+}
+
+func (app *application) mediaFromRequest(r *http.Request) (*data.ExerciseMedia, error) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	mediaID, err := app.readUUIDParam(r, "media_id")
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+	media, err := app.models.ExerciseMedia.Get(mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if media.ExerciseID != exercise.ID {
+		return nil, data.ErrRecordNotFound
+	}
+	return media, nil
+}
+
+func (app *application) createExerciseHandler(w http.ResponseWriter, r *http.Request) {
+	scenarioID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	exists, err := app.models.Scenarios.Exists(scenarioID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Info string `json:"info"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	exercise := &data.Exercise{
+		ScenarioID: scenarioID,
+		Info:       input.Info,
+	}
+	v := validator.New()
+	if data.ValidateExercise(v, exercise); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Exercises.Insert(exercise)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/scenarios/%s/exercises/%s", scenarioID, exercise.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"exercise": exercise}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateExerciseHandler(w http.ResponseWriter, r *http.Request) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Info *string `json:"info"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.Info != nil {
+		exercise.Info = *input.Info
+	}
+	v := validator.New()
+	if data.ValidateExercise(v, exercise); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Exercises.Update(exercise)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"exercise": exercise}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteExerciseHandler(w http.ResponseWriter, r *http.Request) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.models.Exercises.Delete(exercise.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"message": "exercise successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reorderExercisesHandler(w http.ResponseWriter, r *http.Request) {
+	scenarioID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input reorderInput
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.models.Exercises.Reorder(scenarioID, input.IDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrderMismatch):
+			v := validator.New()
+			v.AddError("ids", err.Error())
+			app.failedValidateResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	exercises, err := app.models.Exercises.GetByScenarioID(scenarioID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"exercises": exercises}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createExerciseMediaHandler(w http.ResponseWriter, r *http.Request) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		MediaURL  string         `json:"media_url"`
+		MediaType data.MediaType `json:"media_type"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	media := &data.ExerciseMedia{
+		ExerciseID: exercise.ID,
+		MediaURL:   input.MediaURL,
+		MediaType:  input.MediaType,
+	}
+	v := validator.New()
+	if data.ValidateExerciseMedia(v, media); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.ExerciseMedia.Insert(media)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/scenarios/%s/exercises/%s/media/%s", exercise.ScenarioID, exercise.ID, media.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"media": media}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateExerciseMediaHandler(w http.ResponseWriter, r *http.Request) {
+	media, err := app.mediaFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		MediaURL  *string         `json:"media_url"`
+		MediaType *data.MediaType `json:"media_type"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.MediaURL != nil {
+		media.MediaURL = *input.MediaURL
+	}
+	if input.MediaType != nil {
+		media.MediaType = *input.MediaType
+	}
+	v := validator.New()
+	if data.ValidateExerciseMedia(v, media); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.ExerciseMedia.Update(media)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"media": media}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteExerciseMediaHandler(w http.ResponseWriter, r *http.Request) {
+	media, err := app.mediaFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.models.ExerciseMedia.Delete(media.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"message": "media successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
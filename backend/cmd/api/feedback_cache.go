@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func (app *application) feedbackCacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	v := validator.New()
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		v.Check(err == nil && n >= 1 && n <= 365, "days", "must be a number between 1 and 365")
+		days = n
+	}
+	if !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	stats, err := app.models.FeedbackCache.Stats(days)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"feedback_cache": stats, "ttl": app.config.feedback.cacheTTL.String()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/google/uuid"
+)
+
+const (
+	feedbackStreamPoll      = time.Second
+	feedbackStreamHeartbeat = 15 * time.Second
+	feedbackStreamTimeout   = 5 * time.Minute
+)
+
+func writeEvent(w http.ResponseWriter, event string, payload any) error {
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, js)
+	return err
+}
+
+func (app *application) streamResponseFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	responseID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	sessionResponse, err := app.models.SessionResponses.Get(responseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	session, err := app.models.ScenarioSessions.Get(sessionResponse.ScenarioSessionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	permission, err := app.sessionPermission(r, session)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	student := !permission.Includes(data.PermissionView)
+	held := sessionResponse.FeedbackHeld(session)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	poll := time.NewTicker(feedbackStreamPoll)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(feedbackStreamHeartbeat)
+	defer heartbeat.Stop()
+	timeout := time.After(feedbackStreamTimeout)
+
+	sent := make(map[uuid.UUID]data.FeedbackStatus)
+	for {
+		_ = rc.SetWriteDeadline(time.Now().Add(feedbackStreamHeartbeat * 2))
+		states, err := app.models.ResponseFeedback.GetByResponseID(responseID)
+		if err != nil {
+			app.logger.Error("failed to poll feedback status", "response_id", responseID.String(), "error", err)
+			return
+		}
+		pending := 0
+		for _, state := range states {
+			if student {
+				state = state.ForStudent(held)
+			}
+			if status, ok := sent[state.QuestionID]; !ok || status != state.Status {
+				if err := writeEvent(w, "feedback", state); err != nil {
+					return
+				}
+				sent[state.QuestionID] = state.Status
+			}
+			if state.Status == data.FeedbackPending {
+				pending++
+			}
+		}
+		if pending == 0 {
+			writeEvent(w, "complete", jsonEnvelope{"response_id": responseID, "held": student && held})
+			rc.Flush()
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-app.shutdown:
+			return
+		case <-timeout:
+			writeEvent(w, "timeout", jsonEnvelope{"response_id": responseID, "pending": pending})
+			rc.Flush()
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-poll.C:
+		}
+	}
+}
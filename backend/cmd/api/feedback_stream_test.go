@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+)
+
+func TestWriteEvent(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	err := writeEvent(rr, "feedback", map[string]string{"status": "done"})
+
+	assert.NilError(t, err)
+	assert.Equal(t, rr.Body.String(), "event: feedback\ndata: {\"status\":\"done\"}\n\n")
+}
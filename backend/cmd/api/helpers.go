@@ -31,8 +31,11 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data jsonEn
 }
 
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, data any) error {
-	maxBytes := 1_048_576
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	return app.readJSONLimit(w, r, data, 1_048_576)
+}
+
+func (app *application) readJSONLimit(w http.ResponseWriter, r *http.Request, data any, maxBytes int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	err := decoder.Decode(data)
@@ -72,10 +75,14 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, data an
 }
 
 func (app *application) readIDParam(r *http.Request) (uuid.UUID, error) {
+	return app.readUUIDParam(r, "id")
+}
+
+func (app *application) readUUIDParam(r *http.Request, name string) (uuid.UUID, error) {
 	params := httprouter.ParamsFromContext(r.Context())
-	id, err := uuid.Parse(params.ByName("id"))
+	id, err := uuid.Parse(params.ByName(name))
 	if err != nil {
-		return uuid.Nil, errors.New("invalid id parameter")
+		return uuid.Nil, fmt.Errorf("invalid %s parameter", name)
 	}
 	return id, nil
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/storage"
 	"github.com/berberapan/info-eval/internal/vcs"
 	_ "github.com/lib/pq"
 )
@@ -19,10 +22,17 @@ import (
 var version = vcs.Version()
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
-	wg     sync.WaitGroup
+	config  config
+	logger  *slog.Logger
+	models  data.Models
+	storage storage.Storage
+	llm     llm.Provider
+	wg      sync.WaitGroup
+
+	feedbackWake chan struct{}
+	// shutdown is closed when the server starts shutting down, so long-lived
+	// handlers such as the feedback stream can let go of their connection.
+	shutdown chan struct{}
 }
 
 type config struct {
@@ -40,7 +50,23 @@ type config struct {
 		secret string
 	}
 	ai struct {
-		key string
+		provider string
+		model    string
+		baseURL  string
+		key      string
+		limits   llm.Limits
+	}
+	feedback struct {
+		workers      int
+		pollInterval time.Duration
+		cacheTTL     time.Duration
+	}
+	media struct {
+		dir         string
+		maxImageMB  int
+		maxVideoMB  int
+		maxAudioMB  int
+		maxBundleMB int
 	}
 }
 
@@ -62,7 +88,24 @@ func main() {
 	})
 
 	flag.StringVar(&cfg.jwt.secret, "jwt-secret", "", "JWT Secret")
-	flag.StringVar(&cfg.ai.key, "gemini-key", "", "API key for Gemini")
+	flag.StringVar(&cfg.ai.provider, "llm-provider", "gemini", "LLM provider for feedback (gemini|openai|fake|none)")
+	flag.StringVar(&cfg.ai.model, "llm-model", "", "LLM model name (defaults to the provider's default)")
+	flag.StringVar(&cfg.ai.baseURL, "llm-base-url", "", "Base URL of an OpenAI-compatible API")
+	flag.StringVar(&cfg.ai.key, "llm-api-key", "", "API key for the LLM provider")
+	flag.StringVar(&cfg.ai.key, "gemini-key", "", "API key for Gemini (alias of -llm-api-key)")
+	flag.IntVar(&cfg.ai.limits.Concurrency, "llm-concurrency", 4, "Max concurrent requests to the LLM provider")
+	flag.IntVar(&cfg.ai.limits.RequestsPerMinute, "llm-rpm", 60, "Max requests per minute to the LLM provider (0 for no limit)")
+	flag.DurationVar(&cfg.ai.limits.Timeout, "llm-timeout", 60*time.Second, "Timeout for a single LLM request")
+
+	flag.IntVar(&cfg.feedback.workers, "feedback-workers", 2, "Number of AI feedback workers")
+	flag.DurationVar(&cfg.feedback.pollInterval, "feedback-poll-interval", 5*time.Second, "How often idle feedback workers poll for jobs")
+	flag.DurationVar(&cfg.feedback.cacheTTL, "feedback-cache-ttl", 24*time.Hour, "How long generated feedback is reused for identical answers (0 disables the cache)")
+
+	flag.StringVar(&cfg.media.dir, "media-dir", "./uploads", "Directory for uploaded media files")
+	flag.IntVar(&cfg.media.maxImageMB, "media-max-image-mb", 10, "Max size of uploaded images in MB")
+	flag.IntVar(&cfg.media.maxVideoMB, "media-max-video-mb", 200, "Max size of uploaded videos in MB")
+	flag.IntVar(&cfg.media.maxAudioMB, "media-max-audio-mb", 50, "Max size of uploaded audio files in MB")
+	flag.IntVar(&cfg.media.maxBundleMB, "bundle-max-mb", 300, "Max size of imported scenario bundles, media files included, in MB")
 
 	flag.Parse()
 
@@ -81,10 +124,38 @@ func main() {
 	defer db.Close()
 	logger.Info("database connection pool established")
 
+	mediaStorage, err := storage.NewLocal(cfg.media.dir)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	provider, err := llm.New(llm.Config{
+		Provider: cfg.ai.provider,
+		Model:    cfg.ai.model,
+		BaseURL:  cfg.ai.baseURL,
+		APIKey:   cfg.ai.key,
+	})
+	switch {
+	case errors.Is(err, llm.ErrNoAPIKey):
+		logger.Warn("no API key configured for LLM provider, AI feedback is disabled", "provider", cfg.ai.provider)
+	case err != nil:
+		logger.Error(err.Error())
+		os.Exit(1)
+	case provider != nil:
+		logger.Info("LLM provider configured", "provider", provider.Name(), "concurrency", cfg.ai.limits.Concurrency, "rpm", cfg.ai.limits.RequestsPerMinute)
+		provider = llm.NewLimited(provider, cfg.ai.limits)
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:  cfg,
+		logger:  logger,
+		models:  data.NewModels(db),
+		storage: mediaStorage,
+		llm:     provider,
+
+		feedbackWake: make(chan struct{}, 1),
+		shutdown:     make(chan struct{}),
 	}
 
 	err = app.serve()
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/storage"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func (app *application) mediaLimit(mediaType data.MediaType) int64 {
+	switch mediaType {
+	case data.ImageType:
+		return int64(app.config.media.maxImageMB) << 20
+	case data.VideoType:
+		return int64(app.config.media.maxVideoMB) << 20
+	case data.AudioType:
+		return int64(app.config.media.maxAudioMB) << 20
+	default:
+		return 0
+	}
+}
+
+func (app *application) mediaLimits() data.MediaLimits {
+	limits := make(data.MediaLimits)
+	for _, t := range data.MediaTypes {
+		limits[t] = app.mediaLimit(t)
+	}
+	return limits
+}
+
+func (app *application) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(5 * time.Minute))
+
+	maxBytes := max(app.mediaLimit(data.ImageType), app.mediaLimit(data.VideoType), app.mediaLimit(data.AudioType))
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("body must be multipart/form-data"))
+		return
+	}
+	var file io.Reader
+	for file == nil {
+		part, err := mr.NextPart()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				app.badRequestResponse(w, r, errors.New("body must contain a file field"))
+				return
+			}
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		if part.FormName() == "file" {
+			file = part
+		}
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, io.EOF) {
+			app.badRequestResponse(w, r, errors.New("file is empty"))
+			return
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	mediaType, ok := data.MediaTypeForContentType(contentType)
+	if !ok {
+		v := validator.New()
+		v.AddError("file", fmt.Sprintf("unsupported content type %s", contentType))
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	limit := app.mediaLimit(mediaType)
+
+	tmp, err := os.CreateTemp("", "media-upload-*")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(io.MultiReader(bytes.NewReader(head), file), limit+1))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			app.contentTooLargeResponse(w, r, limit)
+			return
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if written > limit {
+		app.contentTooLargeResponse(w, r, limit)
+		return
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	existing, err := app.models.MediaObjects.GetBySHA256(sum)
+	switch {
+	case err == nil:
+		err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"media_object": existing, "media_url": mediaURL(existing)}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	case !errors.Is(err, data.ErrRecordNotFound):
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.storage.Put(r.Context(), sum, tmp)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	object := &data.MediaObject{
+		SHA256:      sum,
+		ContentType: contentType,
+		MediaType:   mediaType,
+		SizeBytes:   written,
+	}
+	object.UploadedBy.UUID, object.UploadedBy.Valid = app.contextGetUser(r).ID, true
+	created, err := app.models.MediaObjects.Insert(object)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !created {
+		err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"media_object": object, "media_url": mediaURL(object)}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", mediaURL(object))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"media_object": object, "media_url": mediaURL(object)}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) serveMediaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	object, err := app.models.MediaObjects.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	content, err := app.storage.Open(r.Context(), object.SHA256)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer content.Close()
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(10 * time.Minute))
+
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if rs, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", object.CreatedAt, rs)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(object.SizeBytes, 10))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+func mediaURL(object *data.MediaObject) string {
+	return fmt.Sprintf("/v1/media/%s", object.ID)
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/data"
 )
 
 func TestRecoverPanic(t *testing.T) {
@@ -51,3 +52,30 @@ func TestRecoverPanic(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireAdmin(t *testing.T) {
+	app := newTestApplication(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		user           *data.User
+		expectedStatus int
+	}{
+		{name: "Anonymous", user: data.AnonymousUser, expectedStatus: http.StatusUnauthorized},
+		{name: "Teacher", user: &data.User{Email: "larare@example.com"}, expectedStatus: http.StatusForbidden},
+		{name: "Admin", user: &data.User{Email: "admin@example.com", IsAdmin: true}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request := app.contextSetUser(httptest.NewRequest("GET", "/", nil), tt.user)
+
+			app.requireAdmin(next).ServeHTTP(recorder, request)
+			assert.Equal(t, recorder.Code, tt.expectedStatus)
+		})
+	}
+}
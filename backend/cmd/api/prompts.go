@@ -0,0 +1,160 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/feedback"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+func (app *application) listPromptTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	kind := data.PromptKind(r.URL.Query().Get("kind"))
+	v := validator.New()
+	if v.Check(kind == "" || validator.PermittedValues(kind, data.PromptKinds...), "kind", "must be free_text, rubric, summary, class_report or question_drafts"); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	templates, err := app.models.PromptTemplates.GetAll(kind)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	active, err := app.activePromptTemplates()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"prompt_templates": templates, "active": active}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showPromptTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	template, err := app.models.PromptTemplates.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"prompt_template": template}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createPromptTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Kind data.PromptKind `json:"kind"`
+		Body string          `json:"body"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	template := &data.PromptTemplate{
+		Kind:      input.Kind,
+		Body:      input.Body,
+		CreatedBy: uuid.NullUUID{UUID: app.contextGetUser(r).ID, Valid: true},
+	}
+	v := validator.New()
+	if data.ValidatePromptTemplate(v, template); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.PromptTemplates.Insert(template)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/prompt-templates/%s", template.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"prompt_template": template}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) previewPromptTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Kind           data.PromptKind `json:"kind"`
+		Body           string          `json:"body"`
+		Question       string          `json:"question"`
+		PromptGuidance string          `json:"prompt_guidance"`
+		Answer         string          `json:"answer"`
+		Language       data.Language   `json:"language"`
+		Rubric         *data.Rubric    `json:"rubric"`
+		Info           string          `json:"info"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	language := input.Language.OrDefault()
+	question := data.ExerciseQuestion{
+		ExerciseType:   data.FreeTextType,
+		Question:       input.Question,
+		PromptGuidance: sql.NullString{String: input.PromptGuidance, Valid: input.PromptGuidance != ""},
+		Rubric:         input.Rubric,
+	}
+	v := validator.New()
+	v.Check(validator.PermittedValues(input.Kind, data.PromptKinds...), "kind", "must be free_text, rubric, summary, class_report or question_drafts")
+	v.Check(input.Answer != "" || input.Kind == data.PromptQuestionDrafts, "answer", "must be provided")
+	v.Check(input.Info != "" || input.Kind != data.PromptQuestionDrafts, "info", "must be provided for question_drafts templates")
+	v.Check(input.Kind != data.PromptRubric || input.Rubric != nil, "rubric", "must be provided for rubric templates")
+	data.ValidateLanguage(v, "language", language)
+	if data.ValidateExerciseQuestion(v, &question); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	template := &data.PromptTemplate{Kind: input.Kind, Body: input.Body}
+	if input.Body == "" {
+		active, err := app.activePromptTemplates()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		template = active[input.Kind]
+	}
+	vars := feedback.Vars(language, question, input.Answer)
+	switch input.Kind {
+	case data.PromptSummary:
+		vars.Answers = []data.PromptAnswer{feedback.SummaryAnswer(input.Question, input.Answer, "")}
+	case data.PromptClassReport:
+		vars.Answers = feedback.ReportAnswers([]string{input.Answer})
+	case data.PromptQuestionDrafts:
+		vars.Info = input.Info
+		vars.Types = data.ExerciseTypes
+	}
+	prompt, err := template.Render(vars)
+	if err != nil {
+		v.AddError("body", "must be a valid template: "+err.Error())
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"prompt": prompt, "prompt_template": template}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/feedback"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+const questionDraftsTimeout = 3 * time.Minute
+
+func (app *application) questionDraftFromRequest(r *http.Request) (*data.QuestionDraft, error) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	draftID, err := app.readUUIDParam(r, "draft_id")
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+	draft, err := app.models.QuestionDrafts.Get(draftID)
+	if err != nil {
+		return nil, err
+	}
+	if draft.ExerciseID != exercise.ID {
+		return nil, data.ErrRecordNotFound
+	}
+	return draft, nil
+}
+
+// draftMedia describes the exercise's attachments to the model. Uploaded
+// files have no useful name, but their content type says what kind of file
+// the students get; for linked media the file name is often telling.
+func (app *application) draftMedia(exerciseID uuid.UUID) ([]data.PromptMedia, error) {
+	media, err := app.models.ExerciseMedia.GetByExerciseID(exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	var described []data.PromptMedia
+	for _, m := range media {
+		pm := data.PromptMedia{Type: m.MediaType}
+		if id, ok := strings.CutPrefix(m.MediaURL, "/v1/media/"); ok {
+			if objectID, err := uuid.Parse(id); err == nil {
+				object, err := app.models.MediaObjects.Get(objectID)
+				switch {
+				case err == nil:
+					pm.ContentType = object.ContentType
+				case !errors.Is(err, data.ErrRecordNotFound):
+					return nil, err
+				}
+			}
+		} else if u, err := url.Parse(m.MediaURL); err == nil && u.Path != "" && u.Path != "/" {
+			pm.Name = path.Base(u.Path)
+		}
+		described = append(described, pm)
+	}
+	return described, nil
+}
+
+func (app *application) createQuestionDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Types []data.ExerciseType `json:"types"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if len(input.Types) == 0 {
+		input.Types = data.ExerciseTypes
+	}
+	v := validator.New()
+	v.Check(validator.Unique(input.Types), "types", "must not contain duplicate types")
+	for _, t := range input.Types {
+		v.Check(validator.PermittedValues(t, data.ExerciseTypes...), "types", "must only contain supported question types")
+	}
+	if !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	if app.llm == nil {
+		app.errorResponse(w, r, http.StatusServiceUnavailable, "no LLM provider is configured")
+		return
+	}
+	scenario, err := app.models.Scenarios.Get(exercise.ScenarioID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	media, err := app.draftMedia(exercise.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	templates, err := app.activePromptTemplates()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	template := templates[data.PromptQuestionDrafts]
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(questionDraftsTimeout))
+
+	generator := feedback.Generator{LLM: app.llm, Logger: app.logger.With("exercise_id", exercise.ID.String())}
+	questions, err := generator.DraftQuestions(r.Context(), template, scenario.Language.OrDefault(), exercise.Info, media, input.Types)
+	if err != nil {
+		app.logger.Error("Failed to draft questions", "provider", app.llm.Name(), "exercise_id", exercise.ID.String(), "error", err)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	drafts := make([]*data.QuestionDraft, len(questions))
+	for i, q := range questions {
+		drafts[i] = &data.QuestionDraft{
+			ExerciseID:       exercise.ID,
+			Question:         q,
+			PromptTemplateID: feedback.PromptTemplateID(template),
+			CreatedBy:        uuid.NullUUID{UUID: app.contextGetUser(r).ID, Valid: true},
+		}
+	}
+	err = app.models.QuestionDrafts.Insert(drafts)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"question_drafts": drafts}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listQuestionDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	drafts, err := app.models.QuestionDrafts.GetByExerciseID(exercise.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"question_drafts": drafts}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) acceptQuestionDraftHandler(w http.ResponseWriter, r *http.Request) {
+	draft, err := app.questionDraftFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	questionID, err := app.models.QuestionDrafts.Accept(draft.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	question, err := app.models.ExerciseQuestions.Get(questionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	question.Options, err = app.models.QuestionOptions.GetByQuestionID(questionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if question.Options == nil {
+		question.Options = []data.QuestionOption{}
+	}
+	scenarioID, _ := app.readIDParam(r)
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/scenarios/%s/exercises/%s/questions/%s", scenarioID, question.ExerciseID, question.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"question": question}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteQuestionDraftHandler(w http.ResponseWriter, r *http.Request) {
+	draft, err := app.questionDraftFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.models.QuestionDrafts.Delete(draft.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"message": "question draft successfully discarded"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
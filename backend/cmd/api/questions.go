@@ -0,0 +1,429 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func (app *application) questionFromRequest(r *http.Request) (*data.ExerciseQuestion, error) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	questionID, err := app.readUUIDParam(r, "question_id")
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+	question, err := app.models.ExerciseQuestions.Get(questionID)
+	if err != nil {
+		return nil, err
+	}
+	if question.ExerciseID != exercise.ID {
+		return nil, data.ErrRecordNotFound
+	}
+	return question, nil
+}
+
+func (app *application) optionFromRequest(r *http.Request) (*data.QuestionOption, error) {
+	question, err := app.questionFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	optionID, err := app.readUUIDParam(r, "option_id")
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+	option, err := app.models.QuestionOptions.Get(optionID)
+	if err != nil {
+		return nil, err
+	}
+	if option.QuestionID != question.ID {
+		return nil, data.ErrRecordNotFound
+	}
+	return option, nil
+}
+
+func (app *application) createQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Type           data.ExerciseType `json:"type"`
+		Question       string            `json:"question"`
+		PromptGuidance string            `json:"prompt_guidance"`
+		Rubric         *data.Rubric      `json:"rubric"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	question := &data.ExerciseQuestion{
+		ExerciseID:     exercise.ID,
+		ExerciseType:   input.Type,
+		Question:       input.Question,
+		PromptGuidance: sql.NullString{String: input.PromptGuidance, Valid: input.PromptGuidance != ""},
+		Rubric:         input.Rubric,
+	}
+	v := validator.New()
+	if data.ValidateExerciseQuestion(v, question); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.ExerciseQuestions.Insert(question)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	question.Options = []data.QuestionOption{}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/scenarios/%s/exercises/%s/questions/%s", exercise.ScenarioID, exercise.ID, question.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"question": question}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	question, err := app.questionFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Type           *data.ExerciseType `json:"type"`
+		Question       *string            `json:"question"`
+		PromptGuidance *string            `json:"prompt_guidance"`
+		Rubric         json.RawMessage    `json:"rubric"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	typeChanged := input.Type != nil && *input.Type != question.ExerciseType
+	if input.Type != nil {
+		question.ExerciseType = *input.Type
+	}
+	if input.Question != nil {
+		question.Question = *input.Question
+	}
+	if input.PromptGuidance != nil {
+		question.PromptGuidance = sql.NullString{String: *input.PromptGuidance, Valid: *input.PromptGuidance != ""}
+	}
+	if input.Rubric != nil {
+		question.Rubric = nil
+		if string(input.Rubric) != "null" {
+			question.Rubric = &data.Rubric{}
+			if err := json.Unmarshal(input.Rubric, question.Rubric); err != nil {
+				app.badRequestResponse(w, r, fmt.Errorf("body contains an invalid rubric: %w", err))
+				return
+			}
+		}
+	}
+	v := validator.New()
+	if data.ValidateExerciseQuestion(v, question); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	if typeChanged {
+		options, err := app.models.QuestionOptions.GetByQuestionID(question.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// The options have to fit the new type as they are; they're left for
+		// the teacher to fix or remove first rather than dropped here.
+		for i := range options {
+			ov := validator.New()
+			if data.ValidateOptionForType(ov, question.ExerciseType, &options[i]); !ov.Valid() {
+				v.AddError("type", fmt.Sprintf("can't change to %s while the question has options that don't fit it", question.ExerciseType))
+				break
+			}
+		}
+		if !v.Valid() {
+			app.failedValidateResponse(w, r, v.Errors)
+			return
+		}
+	}
+	err = app.models.ExerciseQuestions.Update(question)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	question.Options, err = app.models.QuestionOptions.GetByQuestionID(question.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"question": question}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	question, err := app.questionFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.models.ExerciseQuestions.Delete(question.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"message": "question successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reorderQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	exercise, err := app.exerciseFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input reorderInput
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.models.ExerciseQuestions.Reorder(exercise.ID, input.IDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrderMismatch):
+			v := validator.New()
+			v.AddError("ids", err.Error())
+			app.failedValidateResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	questions, err := app.models.ExerciseQuestions.GetByExerciseID(exercise.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"questions": questions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createOptionHandler(w http.ResponseWriter, r *http.Request) {
+	question, err := app.questionFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		OptionText string `json:"option_text"`
+		IsCorrect  bool   `json:"is_correct"`
+		Feedback   string `json:"feedback"`
+		MatchText  string `json:"match_text"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	option := &data.QuestionOption{
+		QuestionID: question.ID,
+		OptionText: input.OptionText,
+		IsCorrect:  input.IsCorrect,
+		Feedback:   input.Feedback,
+		MatchText:  input.MatchText,
+	}
+	v := validator.New()
+	data.ValidateOptionForType(v, question.ExerciseType, option)
+	if data.ValidateQuestionOption(v, option); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.QuestionOptions.Insert(option)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("%s/%s", r.URL.Path, option.ID))
+	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"option": option}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateOptionHandler(w http.ResponseWriter, r *http.Request) {
+	option, err := app.optionFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		OptionText *string `json:"option_text"`
+		IsCorrect  *bool   `json:"is_correct"`
+		Feedback   *string `json:"feedback"`
+		MatchText  *string `json:"match_text"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if input.OptionText != nil {
+		option.OptionText = *input.OptionText
+	}
+	if input.IsCorrect != nil {
+		option.IsCorrect = *input.IsCorrect
+	}
+	if input.Feedback != nil {
+		option.Feedback = *input.Feedback
+	}
+	if input.MatchText != nil {
+		option.MatchText = *input.MatchText
+	}
+	question, err := app.models.ExerciseQuestions.Get(option.QuestionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	data.ValidateOptionForType(v, question.ExerciseType, option)
+	if data.ValidateQuestionOption(v, option); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.QuestionOptions.Update(option)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"option": option}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteOptionHandler(w http.ResponseWriter, r *http.Request) {
+	option, err := app.optionFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.models.QuestionOptions.Delete(option.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"message": "option successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reorderOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	question, err := app.questionFromRequest(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input reorderInput
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.models.QuestionOptions.Reorder(question.ID, input.IDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOrderMismatch):
+			v := validator.New()
+			v.AddError("ids", err.Error())
+			app.failedValidateResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	options, err := app.models.QuestionOptions.GetByQuestionID(question.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"options": options}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
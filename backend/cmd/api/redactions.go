@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func (app *application) listRedactionsHandler(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	v := validator.New()
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		v.Check(err == nil && n >= 1 && n <= 365, "days", "must be a number between 1 and 365")
+		days = n
+	}
+	if !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	redactions, err := app.models.Redactions.GetRecent(days)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"redactions": redactions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/grading"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
 )
 
 type createSessionResponseInput struct {
@@ -25,21 +29,76 @@ func (app *application) createSessionResponseHandler(w http.ResponseWriter, r *h
 		app.badRequestResponse(w, r, err)
 		return
 	}
+	session, err := app.models.ScenarioSessions.Get(scenarioSessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	scenario, err := app.sessionScenario(session)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	questions := scenario.QuestionMap()
+	v := validator.New()
+	for key, answer := range input.RawAnswers {
+		answerKey := fmt.Sprintf("raw_answers.%s", key)
+		questionID, err := uuid.Parse(key)
+		if err != nil {
+			v.AddError(answerKey, "must be keyed by question id")
+			continue
+		}
+		question, ok := questions[questionID]
+		if !ok {
+			// A page loaded before a question was removed can still send an
+			// answer to it; drop that answer rather than the submission.
+			app.logger.Warn("Dropped answer to a question outside the session's revision", "scenario_session_id", scenarioSessionID.String(), "question_id", key)
+			delete(input.RawAnswers, key)
+			continue
+		}
+		grading.ValidateAnswer(v, answerKey, &question, answer)
+	}
+	if !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
 	rawAnswersBytes, err := json.Marshal(input.RawAnswers)
 	if err != nil {
 		app.serverErrorResponse(w, r, fmt.Errorf("failed to marshal raw_answers: %w", err))
 		return
 	}
+	summary := grading.GradeAll(questions, input.RawAnswers)
+	gradingBytes, err := json.Marshal(summary.Questions)
+	if err != nil {
+		app.serverErrorResponse(w, r, fmt.Errorf("failed to marshal grading: %w", err))
+		return
+	}
+	var feedbackQuestions []uuid.UUID
+	for key := range input.RawAnswers {
+		questionID := uuid.MustParse(key)
+		if questions[questionID].ExerciseType == data.FreeTextType {
+			feedbackQuestions = append(feedbackQuestions, questionID)
+		}
+	}
 	sessionResponse := data.SessionResponse{
 		ScenarioSessionID: scenarioSessionID,
 		RawAnswers:        rawAnswersBytes,
+		Grading:           gradingBytes,
+		Score:             &summary.Score,
+		MaxScore:          &summary.MaxScore,
+		FeedbackQuestions: feedbackQuestions,
 	}
 	createdResponse, err := app.models.SessionResponses.Create(sessionResponse)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	app.triggerAIFeedbackGeneration(createdResponse.ID, createdResponse.ScenarioSessionID, rawAnswersBytes)
+	app.notifyFeedbackWorkers()
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/sessions/%s/responses/%s", scenarioSessionID, createdResponse.ID))
 	err = app.writeJSON(w, http.StatusCreated, jsonEnvelope{"session_response": createdResponse}, headers)
@@ -48,6 +107,40 @@ func (app *application) createSessionResponseHandler(w http.ResponseWriter, r *h
 	}
 }
 
+func (app *application) sessionPermission(r *http.Request, session *data.ScenarioSession) (data.Permission, error) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		return data.PermissionNone, nil
+	}
+	return app.models.ScenarioShares.GetPermission(session.ScenarioID, user)
+}
+
+// applyFeedbackStates fills in the per-question feedback. Teachers see the
+// model output next to their own version, students only the version they're
+// meant to read.
+func applyFeedbackStates(output *data.SessionResponseOutput, states []data.ResponseFeedback, student bool) {
+	for _, state := range states {
+		if student {
+			state = state.ForStudent(output.FeedbackHeld)
+		}
+		if text := state.Visible(); text != "" {
+			if output.AIFeedback == nil {
+				output.AIFeedback = make(map[string]string)
+			}
+			output.AIFeedback[state.QuestionID.String()] = text
+		} else if state.Held {
+			delete(output.AIFeedback, state.QuestionID.String())
+		}
+		output.FeedbackStatus = append(output.FeedbackStatus, state)
+	}
+	if student && (output.FeedbackHeld || slices.ContainsFunc(output.FeedbackStatus, func(s data.ResponseFeedback) bool { return s.Held })) {
+		output.AISummary = nil
+	}
+	if student && output.FeedbackHeld {
+		output.AIFeedback = nil
+	}
+}
+
 func (app *application) getSessionResponseHandler(w http.ResponseWriter, r *http.Request) {
 	responseID, err := app.readIDParam(r)
 	if err != nil {
@@ -64,10 +157,26 @@ func (app *application) getSessionResponseHandler(w http.ResponseWriter, r *http
 		}
 		return
 	}
+	session, err := app.models.ScenarioSessions.Get(sessionResponse.ScenarioSessionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	permission, err := app.sessionPermission(r, session)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	output := data.SessionResponseOutput{
-		ID:                sessionResponse.ID,
-		ScenarioSessionID: sessionResponse.ScenarioSessionID,
-		SubmittedAt:       sessionResponse.SubmittedAt,
+		ID:                 sessionResponse.ID,
+		ScenarioSessionID:  sessionResponse.ScenarioSessionID,
+		SubmittedAt:        sessionResponse.SubmittedAt,
+		Grading:            sessionResponse.Grading,
+		Score:              sessionResponse.Score,
+		MaxScore:           sessionResponse.MaxScore,
+		FeedbackHeld:       sessionResponse.FeedbackHeld(session),
+		FeedbackReleasedAt: sessionResponse.FeedbackReleasedAt,
+		AISummary:          sessionResponse.AISummary,
 	}
 	if sessionResponse.RawAnswers != nil {
 		if errUnmarshal := json.Unmarshal(sessionResponse.RawAnswers, &output.RawAnswers); errUnmarshal != nil {
@@ -79,6 +188,12 @@ func (app *application) getSessionResponseHandler(w http.ResponseWriter, r *http
 			app.serverErrorResponse(w, r, err)
 		}
 	}
+	states, err := app.models.ResponseFeedback.GetByResponseID(responseID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	applyFeedbackStates(&output, states, !permission.Includes(data.PermissionView))
 	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"session_response": output}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -91,17 +206,52 @@ func (app *application) listSessionResponsesHandler(w http.ResponseWriter, r *ht
 		app.badRequestResponse(w, r, errors.New("invalid scenario session ID parameter"))
 		return
 	}
+	session, err := app.models.ScenarioSessions.Get(scenarioSessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	permission, err := app.sessionPermission(r, session)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !permission.Includes(data.PermissionView) {
+		app.notPermittedResponse(w, r)
+		return
+	}
 	responses, err := app.models.SessionResponses.GetAllByScenarioSessionID(scenarioSessionID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	states, err := app.models.ResponseFeedback.GetBySessionID(scenarioSessionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	outputResponses := make([]data.SessionResponseOutput, len(responses))
 	for i, sr := range responses {
 		output := data.SessionResponseOutput{
-			ID:                sr.ID,
-			ScenarioSessionID: sr.ScenarioSessionID,
-			SubmittedAt:       sr.SubmittedAt,
+			ID:                 sr.ID,
+			ScenarioSessionID:  sr.ScenarioSessionID,
+			SubmittedAt:        sr.SubmittedAt,
+			Grading:            sr.Grading,
+			Score:              sr.Score,
+			MaxScore:           sr.MaxScore,
+			FeedbackHeld:       sr.FeedbackHeld(session),
+			FeedbackReleasedAt: sr.FeedbackReleasedAt,
+			AISummary:          sr.AISummary,
 		}
 		if sr.RawAnswers != nil {
 			if errUnmarshal := json.Unmarshal(sr.RawAnswers, &output.RawAnswers); errUnmarshal != nil {
@@ -113,6 +263,7 @@ func (app *application) listSessionResponsesHandler(w http.ResponseWriter, r *ht
 				app.logger.Error("Failed to unmarshal ai_feedback for list output", "response_id", sr.ID, "error", errUnmarshal)
 			}
 		}
+		applyFeedbackStates(&output, states[sr.ID], false)
 		outputResponses[i] = output
 	}
 	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"session_responses": outputResponses}, nil)
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/google/uuid"
+)
+
+func TestApplyFeedbackStates(t *testing.T) {
+	edited := data.ResponseFeedback{QuestionID: uuid.New(), Status: data.FeedbackDone, Feedback: "Modellens text", ReviewStatus: data.ReviewEdited, TeacherFeedback: "Lärarens text"}
+	pending := data.ResponseFeedback{QuestionID: uuid.New(), Status: data.FeedbackPending}
+	states := []data.ResponseFeedback{edited, pending}
+
+	tests := []struct {
+		name         string
+		held         bool
+		student      bool
+		wantFeedback string
+		wantOriginal string
+		wantSummary  bool
+	}{
+		{name: "Teacher", student: false, held: true, wantFeedback: "Lärarens text", wantOriginal: "Modellens text", wantSummary: true},
+		{name: "Student", student: true, wantFeedback: "Lärarens text", wantOriginal: "Lärarens text", wantSummary: true},
+		{name: "Student while held", student: true, held: true, wantFeedback: "", wantOriginal: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := data.SessionResponseOutput{FeedbackHeld: tt.held, AISummary: &data.FeedbackSummary{NextStep: "Kolla avsändaren"}}
+			applyFeedbackStates(&output, states, tt.student)
+
+			assert.Equal(t, output.AIFeedback[edited.QuestionID.String()], tt.wantFeedback)
+			assert.Equal(t, len(output.FeedbackStatus), 2)
+			assert.Equal(t, output.FeedbackStatus[0].Feedback, tt.wantOriginal)
+			assert.Equal(t, output.FeedbackStatus[0].Held, tt.student && tt.held)
+			assert.Equal(t, output.FeedbackStatus[1].Status, data.FeedbackPending)
+			assert.Equal(t, output.AISummary != nil, tt.wantSummary)
+		})
+	}
+}
+
+func TestApplyFeedbackStatesHidesSummaryWhileFlagged(t *testing.T) {
+	flagged := data.ResponseFeedback{QuestionID: uuid.New(), Status: data.FeedbackDone, Feedback: "Modellens text", ReviewStatus: data.ReviewUnreviewed, Flags: []string{"override_instructions"}}
+	output := data.SessionResponseOutput{AISummary: &data.FeedbackSummary{NextStep: "Kolla avsändaren"}}
+
+	applyFeedbackStates(&output, []data.ResponseFeedback{flagged}, true)
+
+	assert.Equal(t, output.AISummary == nil, true)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+func (app *application) reviewableResponse(w http.ResponseWriter, r *http.Request) (*data.SessionResponse, bool) {
+	responseID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+	sessionResponse, err := app.models.SessionResponses.Get(responseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	session, err := app.models.ScenarioSessions.Get(sessionResponse.ScenarioSessionID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+	permission, err := app.sessionPermission(r, session)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	if !permission.Includes(data.PermissionEdit) {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+	return sessionResponse, true
+}
+
+func (app *application) reviewResponseFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	sessionResponse, ok := app.reviewableResponse(w, r)
+	if !ok {
+		return
+	}
+	questionID, err := app.readUUIDParam(r, "question_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		ReviewStatus data.ReviewStatus `json:"review_status"`
+		Feedback     string            `json:"feedback"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	states, err := app.models.ResponseFeedback.GetByResponseID(sessionResponse.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var state *data.ResponseFeedback
+	for i := range states {
+		if states[i].QuestionID == questionID {
+			state = &states[i]
+		}
+	}
+	if state == nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	state.ReviewStatus = input.ReviewStatus
+	state.TeacherFeedback = input.Feedback
+	state.ReviewedBy = uuid.NullUUID{UUID: app.contextGetUser(r).ID, Valid: true}
+	v := validator.New()
+	if data.ValidateFeedbackReview(v, state); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.ResponseFeedback.Review(state)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"feedback": state}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) releaseResponseFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	sessionResponse, ok := app.reviewableResponse(w, r)
+	if !ok {
+		return
+	}
+	releasedAt, err := app.models.SessionResponses.ReleaseFeedback(sessionResponse.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"response_id": sessionResponse.ID, "feedback_released_at": releasedAt}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) releaseSessionFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	session, err := app.models.ScenarioSessions.Get(sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	permission, err := app.sessionPermission(r, session)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !permission.Includes(data.PermissionEdit) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	released, err := app.models.SessionResponses.ReleaseAllFeedback(session.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"released": released}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/berberapan/info-eval/internal/data"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -17,9 +18,54 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/scenario/:id", app.showScenarioHandler)
 	router.HandlerFunc(http.MethodGet, "/v1/scenarios", app.showScenariosHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/scenarios", app.requireAuthenticatedUser(http.HandlerFunc(app.createScenarioHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/scenarios/:id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.updateScenarioHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/scenarios/:id", app.requireScenarioPermission(data.PermissionOwner, http.HandlerFunc(app.deleteScenarioHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/clone", app.requireAuthenticatedUser(http.HandlerFunc(app.cloneScenarioHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/scenarios/:id/bundle", app.requireScenarioPermission(data.PermissionView, http.HandlerFunc(app.exportScenarioBundleHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/scenarios/:id/shares", app.requireScenarioPermission(data.PermissionOwner, http.HandlerFunc(app.listScenarioSharesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/shares", app.requireScenarioPermission(data.PermissionOwner, http.HandlerFunc(app.shareScenarioHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/scenarios/:id/shares/:user_id", app.requireScenarioPermission(data.PermissionOwner, http.HandlerFunc(app.unshareScenarioHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/scenario-bundles", app.requireAuthenticatedUser(http.HandlerFunc(app.importScenarioBundleHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/exercises", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.createExerciseHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/scenarios/:id/exercise-order", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.reorderExercisesHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/scenarios/:id/exercises/:exercise_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.updateExerciseHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/scenarios/:id/exercises/:exercise_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.deleteExerciseHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/exercises/:exercise_id/media", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.createExerciseMediaHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/scenarios/:id/exercises/:exercise_id/media/:media_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.updateExerciseMediaHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/scenarios/:id/exercises/:exercise_id/media/:media_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.deleteExerciseMediaHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/exercises/:exercise_id/questions", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.createQuestionHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/scenarios/:id/exercises/:exercise_id/question-order", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.reorderQuestionsHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/scenarios/:id/exercises/:exercise_id/questions/:question_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.updateQuestionHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/scenarios/:id/exercises/:exercise_id/questions/:question_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.deleteQuestionHandler)))
+
+	router.HandlerFunc(http.MethodGet, "/v1/scenarios/:id/exercises/:exercise_id/question-drafts", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.listQuestionDraftsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/exercises/:exercise_id/question-drafts", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.createQuestionDraftsHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/exercises/:exercise_id/question-drafts/:draft_id/accept", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.acceptQuestionDraftHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/scenarios/:id/exercises/:exercise_id/question-drafts/:draft_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.deleteQuestionDraftHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/scenarios/:id/exercises/:exercise_id/questions/:question_id/options", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.createOptionHandler)))
+	router.HandlerFunc(http.MethodPut, "/v1/scenarios/:id/exercises/:exercise_id/questions/:question_id/option-order", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.reorderOptionsHandler)))
+	router.HandlerFunc(http.MethodPatch, "/v1/scenarios/:id/exercises/:exercise_id/questions/:question_id/options/:option_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.updateOptionHandler)))
+	router.HandlerFunc(http.MethodDelete, "/v1/scenarios/:id/exercises/:exercise_id/questions/:question_id/options/:option_id", app.requireScenarioPermission(data.PermissionEdit, http.HandlerFunc(app.deleteOptionHandler)))
+
+	router.HandlerFunc(http.MethodPost, "/v1/media", app.requireAuthenticatedUser(http.HandlerFunc(app.uploadMediaHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/media/:id", app.serveMediaHandler)
+
+	router.HandlerFunc(http.MethodGet, "/v1/prompt-templates", app.requireAdmin(http.HandlerFunc(app.listPromptTemplatesHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/prompt-templates", app.requireAdmin(http.HandlerFunc(app.createPromptTemplateHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/prompt-templates/:id", app.requireAdmin(http.HandlerFunc(app.showPromptTemplateHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/prompt-templates/preview", app.requireAdmin(http.HandlerFunc(app.previewPromptTemplateHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/feedback-cache", app.requireAdmin(http.HandlerFunc(app.feedbackCacheStatsHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/redactions", app.requireAdmin(http.HandlerFunc(app.listRedactionsHandler)))
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.requireAuthenticatedUser(http.HandlerFunc(app.registerUserHandle)))
 	router.HandlerFunc(http.MethodGet, "/v1/users/me", app.requireAuthenticatedUser(http.HandlerFunc(app.userProfileHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/scenarios", app.requireAuthenticatedUser(http.HandlerFunc(app.showAuthorScenariosHandler)))
 
 	router.HandlerFunc(http.MethodPost, "/v1/authentication", app.createAuthenticationTokenHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/logout", app.requireAuthenticatedUser(http.HandlerFunc(app.removeAuthenticationTokenHandler)))
@@ -27,9 +73,15 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodPost, "/v1/sessions", app.requireAuthenticatedUser(http.HandlerFunc(app.createScenarioSessionHandler)))
 	router.HandlerFunc(http.MethodGet, "/v1/sessions/:id", app.getScenarioSessionHandler)
 	router.HandlerFunc(http.MethodGet, "/v1/sessions/:id/scenario", app.getScenarioIDHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/sessions/:id/revision", app.getSessionRevisionHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/sessions/:id/responses", app.createSessionResponseHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/sessions/:id/responses", app.listSessionResponsesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/sessions/:id/responses", app.requireAuthenticatedUser(http.HandlerFunc(app.listSessionResponsesHandler)))
+	router.HandlerFunc(http.MethodGet, "/v1/sessions/:id/questions/:question_id/report", app.requireAuthenticatedUser(http.HandlerFunc(app.classReportHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/sessions/:id/release-feedback", app.requireAuthenticatedUser(http.HandlerFunc(app.releaseSessionFeedbackHandler)))
 	router.HandlerFunc(http.MethodGet, "/v1/session-responses/:id", app.getSessionResponseHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/session-responses/:id/feedback", app.streamResponseFeedbackHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/session-responses/:id/feedback/:question_id", app.requireAuthenticatedUser(http.HandlerFunc(app.reviewResponseFeedbackHandler)))
+	router.HandlerFunc(http.MethodPost, "/v1/session-responses/:id/release", app.requireAuthenticatedUser(http.HandlerFunc(app.releaseResponseFeedbackHandler)))
 
 	return app.recoverPanic(app.enableCORS(app.authenticate(router)))
 }
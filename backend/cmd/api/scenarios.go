@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/berberapan/info-eval/internal/data"
 	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
 )
 
 func (app *application) showScenarioHandler(w http.ResponseWriter, r *http.Request) {
@@ -25,6 +27,21 @@ func (app *application) showScenarioHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
+	permission := data.PermissionNone
+	if user := app.contextGetUser(r); !user.IsAnonymous() {
+		permission, err = app.models.ScenarioShares.GetPermission(scenario.ID, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if !permission.Includes(data.PermissionView) {
+		if scenario.Status == data.ScenarioDraft {
+			app.notFoundResponse(w, r)
+			return
+		}
+		scenario = scenario.ForStudent()
+	}
 	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"scenario": scenario}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -32,7 +49,28 @@ func (app *application) showScenarioHandler(w http.ResponseWriter, r *http.Reque
 }
 
 func (app *application) showScenariosHandler(w http.ResponseWriter, r *http.Request) {
-	scenarios, err := app.models.Scenarios.GetAll()
+	scenarios, err := app.models.Scenarios.GetAllByStatus(data.ScenarioPublished)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"scenarios": scenarios}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showAuthorScenariosHandler(w http.ResponseWriter, r *http.Request) {
+	status := data.ScenarioStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		status = data.ScenarioDraft
+	}
+	v := validator.New()
+	if v.Check(validator.PermittedValues(status, data.ScenarioStatuses...), "status", "must be draft, published or archived"); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	scenarios, err := app.models.Scenarios.GetAllForUser(app.contextGetUser(r).ID, status)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -45,9 +83,10 @@ func (app *application) showScenariosHandler(w http.ResponseWriter, r *http.Requ
 
 func (app *application) createScenarioHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Difficulty  int16  `json:"difficulty"`
+		Title       string        `json:"title"`
+		Description string        `json:"description"`
+		Difficulty  int16         `json:"difficulty"`
+		Language    data.Language `json:"language"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -58,6 +97,9 @@ func (app *application) createScenarioHandler(w http.ResponseWriter, r *http.Req
 		Title:       input.Title,
 		Description: input.Description,
 		Difficulty:  input.Difficulty,
+		Status:      data.ScenarioDraft,
+		Language:    input.Language.OrDefault(),
+		OwnerID:     uuid.NullUUID{UUID: app.contextGetUser(r).ID, Valid: true},
 	}
 	v := validator.New()
 	if data.ValidateScenario(v, scenario); !v.Valid() {
@@ -77,13 +119,125 @@ func (app *application) createScenarioHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
+func (app *application) updateScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	scenario, err := app.models.Scenarios.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	var input struct {
+		Title       *string              `json:"title"`
+		Description *string              `json:"description"`
+		Difficulty  *int16               `json:"difficulty"`
+		Status      *data.ScenarioStatus `json:"status"`
+		Language    *data.Language       `json:"language"`
+		Version     *int32               `json:"version"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if v.Check(input.Version != nil, "version", "must be provided"); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	if *input.Version != scenario.Version {
+		app.editConflictResponse(w, r)
+		return
+	}
+	if input.Title != nil {
+		scenario.Title = *input.Title
+	}
+	if input.Description != nil {
+		scenario.Description = *input.Description
+	}
+	if input.Difficulty != nil {
+		scenario.Difficulty = *input.Difficulty
+	}
+	if input.Status != nil {
+		scenario.Status = *input.Status
+	}
+	if input.Language != nil {
+		scenario.Language = *input.Language
+	}
+	if data.ValidateScenario(v, scenario); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Scenarios.Update(scenario)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"scenario": scenario}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	v := validator.New()
+	version, err := strconv.ParseInt(r.URL.Query().Get("version"), 10, 32)
+	if v.Check(err == nil, "version", "must be provided as an integer query parameter"); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	exists, err := app.models.Scenarios.Exists(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.Scenarios.Delete(id, int32(version))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrScenarioInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the scenario has sessions with student work and can't be deleted, archive it instead")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"message": "scenario successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) getScenarioIDHandler(w http.ResponseWriter, r *http.Request) {
 	sessionID, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
-	scenarioID, err := app.models.ScenarioSessions.GetScenarioByID(sessionID)
+	session, err := app.models.ScenarioSessions.Get(sessionID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -93,7 +247,7 @@ func (app *application) getScenarioIDHandler(w http.ResponseWriter, r *http.Requ
 		}
 		return
 	}
-	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"scenario_id": scenarioID}, nil)
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"scenario_id": session.ScenarioID, "scenario_revision_id": session.ScenarioRevisionID}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
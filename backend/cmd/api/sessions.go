@@ -9,14 +9,28 @@ import (
 	"time"
 
 	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
 	"github.com/google/uuid"
 )
 
+func (app *application) sessionScenario(session *data.ScenarioSession) (*data.Scenario, error) {
+	if session.ScenarioRevisionID.Valid {
+		revision, err := app.models.ScenarioRevisions.Get(session.ScenarioRevisionID.UUID)
+		if err != nil {
+			return nil, err
+		}
+		return revision.Scenario, nil
+	}
+	return app.models.Scenarios.Get(session.ScenarioID)
+}
+
 func (app *application) createScenarioSessionHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		ScenarioID            string `json:"scenario_id"`
-		Notes                 string `json:"notes"`
-		ValidityDurationHours int    `json:"validity_duration_hours"`
+		ScenarioID            string         `json:"scenario_id"`
+		Notes                 string         `json:"notes"`
+		Language              *data.Language `json:"language"`
+		HoldFeedback          bool           `json:"hold_feedback"`
+		ValidityDurationHours int            `json:"validity_duration_hours"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -28,6 +42,47 @@ func (app *application) createScenarioSessionHandler(w http.ResponseWriter, r *h
 		app.badRequestResponse(w, r, errors.New("invalid scenario_id format"))
 		return
 	}
+	scenario, err := app.models.Scenarios.Get(scenarioID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v := validator.New()
+			v.AddError("scenario_id", "scenario does not exist")
+			app.failedValidateResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	permission, err := app.models.ScenarioShares.GetPermission(scenario.ID, app.contextGetUser(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !permission.Includes(data.PermissionView) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	if scenario.Status != data.ScenarioPublished {
+		v := validator.New()
+		v.AddError("scenario_id", "sessions can only be created for published scenarios")
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	language := scenario.Language
+	if input.Language != nil {
+		language = *input.Language
+		v := validator.New()
+		if data.ValidateLanguage(v, "language", language); !v.Valid() {
+			app.failedValidateResponse(w, r, v.Errors)
+			return
+		}
+	}
+	revision, err := app.models.ScenarioRevisions.Snapshot(scenario)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	tokenBytes := make([]byte, 16)
 	_, err = rand.Read(tokenBytes)
 	if err != nil {
@@ -42,10 +97,13 @@ func (app *application) createScenarioSessionHandler(w http.ResponseWriter, r *h
 	}
 	expiresAt := time.Now().Add(validityDuration)
 	session := &data.ScenarioSession{
-		ScenarioID: scenarioID,
-		Token:      tokenString,
-		Notes:      input.Notes,
-		ExpiresAt:  expiresAt,
+		ScenarioID:         scenarioID,
+		ScenarioRevisionID: uuid.NullUUID{UUID: revision.ID, Valid: true},
+		Token:              tokenString,
+		Notes:              input.Notes,
+		Language:           language,
+		HoldFeedback:       input.HoldFeedback,
+		ExpiresAt:          expiresAt,
 	}
 	err = app.models.ScenarioSessions.Create(session)
 	if err != nil {
@@ -86,3 +144,65 @@ func (app *application) getScenarioSessionHandler(w http.ResponseWriter, r *http
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) getSessionRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	session, err := app.models.ScenarioSessions.Get(sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	permission, err := app.sessionPermission(r, session)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	student := !permission.Includes(data.PermissionView)
+	if !session.ScenarioRevisionID.Valid {
+		scenario, err := app.models.Scenarios.Get(session.ScenarioID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		if student {
+			scenario = scenario.ForStudent()
+		}
+		err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"revision": nil, "scenario": scenario}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	revision, err := app.models.ScenarioRevisions.Get(session.ScenarioRevisionID.UUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	scenario := revision.Scenario
+	if student {
+		scenario = scenario.ForStudent()
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"revision": revision.Revision, "scenario": scenario}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func (app *application) listScenarioSharesHandler(w http.ResponseWriter, r *http.Request) {
+	scenarioID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	shares, err := app.models.ScenarioShares.GetAllForScenario(scenarioID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"shares": shares}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) shareScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	scenarioID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Email      string          `json:"email"`
+		Permission data.Permission `json:"permission"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	share := &data.ScenarioShare{
+		ScenarioID: scenarioID,
+		Email:      input.Email,
+		Permission: input.Permission,
+	}
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	if data.ValidateScenarioShare(v, share); !v.Valid() {
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no user with this email exists")
+			app.failedValidateResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if user.ID == app.contextGetUser(r).ID {
+		v.AddError("email", "you can't share a scenario with yourself")
+		app.failedValidateResponse(w, r, v.Errors)
+		return
+	}
+	share.UserID = user.ID
+	share.Email = user.Email
+	err = app.models.ScenarioShares.Upsert(share)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"share": share}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unshareScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	scenarioID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	userID, err := app.readUUIDParam(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.models.ScenarioShares.Delete(scenarioID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, jsonEnvelope{"message": "share successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
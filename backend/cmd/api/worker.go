@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+)
+
+const (
+	feedbackJobLease    = 5 * time.Minute
+	feedbackHeartbeat   = time.Minute
+	feedbackBaseBackoff = 10 * time.Second
+	feedbackMaxBackoff  = time.Hour
+)
+
+func feedbackBackoff(attempts int) time.Duration {
+	backoff := feedbackBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= feedbackMaxBackoff {
+			return feedbackMaxBackoff
+		}
+	}
+	return backoff
+}
+
+func (app *application) notifyFeedbackWorkers() {
+	select {
+	case app.feedbackWake <- struct{}{}:
+	default:
+	}
+}
+
+func (app *application) startFeedbackWorkers(ctx context.Context) {
+	if app.llm == nil {
+		app.logger.Warn("no LLM provider configured, feedback jobs will stay pending")
+		return
+	}
+	requeued, err := app.models.FeedbackJobs.RequeueStale(feedbackJobLease)
+	if err != nil {
+		app.logger.Error("failed to requeue stale feedback jobs", "error", err)
+	} else if requeued > 0 {
+		app.logger.Info("requeued stale feedback jobs", "count", requeued)
+	}
+	for i := 0; i < app.config.feedback.workers; i++ {
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+			app.runFeedbackWorker(ctx)
+		}()
+	}
+	app.logger.Info("started feedback workers", "count", app.config.feedback.workers)
+}
+
+func (app *application) runFeedbackWorker(ctx context.Context) {
+	ticker := time.NewTicker(app.config.feedback.pollInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil && app.processFeedbackJob(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := app.models.FeedbackJobs.RequeueStale(feedbackJobLease); err != nil {
+				app.logger.Error("failed to requeue stale feedback jobs", "error", err)
+			}
+			if _, err := app.models.FeedbackCache.DeleteExpired(); err != nil {
+				app.logger.Error("failed to delete expired feedback cache entries", "error", err)
+			}
+		case <-app.feedbackWake:
+		}
+	}
+}
+
+// heartbeatFeedbackJob keeps the job's lease fresh until ctx is done, so a
+// slow job isn't claimed again by another worker while it's still running.
+func (app *application) heartbeatFeedbackJob(ctx context.Context, job *data.FeedbackJob) {
+	ticker := time.NewTicker(feedbackHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := app.models.FeedbackJobs.Heartbeat(job); err != nil {
+				app.logger.Error("failed to renew feedback job lease", "job_id", job.ID.String(), "error", err)
+			}
+		}
+	}
+}
+
+func (app *application) processFeedbackJob(ctx context.Context) bool {
+	job, err := app.models.FeedbackJobs.Claim()
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			app.logger.Error("failed to claim feedback job", "error", err)
+		}
+		return false
+	}
+	jobCtx, stopHeartbeat := context.WithCancel(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		app.heartbeatFeedbackJob(jobCtx, job)
+	}()
+	err = app.generateAndStoreAIFeedback(jobCtx, job.SessionResponseID)
+	stopHeartbeat()
+	<-heartbeatDone
+	if ctx.Err() != nil {
+		if err := app.models.FeedbackJobs.Release(job); err != nil {
+			app.logger.Error("failed to release interrupted feedback job", "job_id", job.ID.String(), "error", err)
+		}
+		return false
+	}
+	if err == nil {
+		err = app.models.FeedbackJobs.Complete(job)
+		switch {
+		case errors.Is(err, data.ErrJobLost):
+			app.logger.Warn("feedback job was taken over before it completed", "job_id", job.ID.String())
+		case err != nil:
+			app.logger.Error("failed to complete feedback job", "job_id", job.ID.String(), "error", err)
+		}
+		return true
+	}
+	jobErr := err
+	err = app.models.FeedbackJobs.Fail(job, jobErr, feedbackBackoff(job.Attempts))
+	switch {
+	case errors.Is(err, data.ErrJobLost):
+		app.logger.Warn("feedback job was taken over before its failure was recorded", "job_id", job.ID.String(), "error", jobErr)
+		return true
+	case err != nil:
+		app.logger.Error("failed to record feedback job failure", "job_id", job.ID.String(), "error", err)
+		return true
+	}
+	if job.Status == data.FeedbackJobDead {
+		err = app.models.ResponseFeedback.FailPending(job.SessionResponseID, jobErr.Error())
+		if err != nil {
+			app.logger.Error("failed to mark feedback as failed", "session_response_id", job.SessionResponseID.String(), "error", err)
+		}
+		app.logger.Error("feedback job exhausted its attempts", "job_id", job.ID.String(), "session_response_id", job.SessionResponseID.String(), "attempts", job.Attempts, "error", jobErr)
+	} else {
+		app.logger.Warn("feedback job failed, retrying", "job_id", job.ID.String(), "attempts", job.Attempts, "run_at", job.RunAt, "error", jobErr)
+	}
+	return true
+}
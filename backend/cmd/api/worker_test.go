@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/assert"
+)
+
+func TestFeedbackBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, feedbackBackoff(tt.attempts), tt.want)
+	}
+}
+
+func TestNotifyFeedbackWorkersDoesNotBlock(t *testing.T) {
+	app := newTestApplication(t)
+	app.feedbackWake = make(chan struct{}, 1)
+
+	app.notifyFeedbackWorkers()
+	app.notifyFeedbackWorkers()
+
+	assert.Equal(t, len(app.feedbackWake), 1)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/storage"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  bundle export -db-dsn=DSN -id=SCENARIO_ID [-media-dir=DIR] [-out=FILE]")
+	fmt.Fprintln(os.Stderr, "  bundle import -db-dsn=DSN -owner=EMAIL [-media-dir=DIR] [-in=FILE]")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dsn := fs.String("db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN")
+	idStr := fs.String("id", "", "ID of the scenario to export")
+	mediaDir := fs.String("media-dir", "./uploads", "Directory for uploaded media files")
+	out := fs.String("out", "", "Output file (default stdout)")
+	fs.Parse(args)
+
+	id, err := uuid.Parse(*idStr)
+	if err != nil {
+		return fmt.Errorf("invalid scenario id %q", *idStr)
+	}
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store, err := storage.NewLocal(*mediaDir)
+	if err != nil {
+		return err
+	}
+	models := data.NewModels(db)
+	scenario, err := models.Scenarios.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to load scenario %s: %w", id, err)
+	}
+	bundle := data.NewScenarioBundle(scenario)
+	if err := models.Bundles.PackFiles(context.Background(), bundle, store); err != nil {
+		return fmt.Errorf("failed to pack media files: %w", err)
+	}
+	js, err := json.MarshalIndent(bundle, "", "\t")
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+	if *out == "" {
+		_, err = os.Stdout.Write(js)
+		return err
+	}
+	return os.WriteFile(*out, js, 0o644)
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dsn := fs.String("db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN")
+	in := fs.String("in", "", "Input file (default stdin)")
+	mediaDir := fs.String("media-dir", "./uploads", "Directory for uploaded media files")
+	maxImageMB := fs.Int("media-max-image-mb", 10, "Max size of included images in MB")
+	maxVideoMB := fs.Int("media-max-video-mb", 200, "Max size of included videos in MB")
+	maxAudioMB := fs.Int("media-max-audio-mb", 50, "Max size of included audio files in MB")
+	owner := fs.String("owner", "", "Email of the user who will own the imported scenario")
+	fs.Parse(args)
+
+	if *owner == "" {
+		return fmt.Errorf("-owner is required")
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var bundle data.ScenarioBundle
+	if err := decoder.Decode(&bundle); err != nil {
+		return fmt.Errorf("failed to decode bundle: %w", err)
+	}
+	v := validator.New()
+	data.ValidateScenarioBundle(v, &bundle)
+	data.ValidateBundleFiles(v, bundle.Files, data.MediaLimits{
+		data.ImageType: int64(*maxImageMB) << 20,
+		data.VideoType: int64(*maxVideoMB) << 20,
+		data.AudioType: int64(*maxAudioMB) << 20,
+	})
+	if !v.Valid() {
+		return validationError(v)
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	models := data.NewModels(db)
+	user, err := models.Users.GetByEmail(*owner)
+	if err != nil {
+		return fmt.Errorf("failed to find owner %q: %w", *owner, err)
+	}
+	ownerID := uuid.NullUUID{UUID: user.ID, Valid: true}
+	store, err := storage.NewLocal(*mediaDir)
+	if err != nil {
+		return err
+	}
+	err = models.Bundles.CheckUploadedMedia(v, &bundle)
+	if err != nil {
+		return fmt.Errorf("failed to check media files: %w", err)
+	}
+	if !v.Valid() {
+		return validationError(v)
+	}
+	scenarioID, err := models.Bundles.Import(&bundle, ownerID, uuid.NullUUID{}, store)
+	if err != nil {
+		return fmt.Errorf("import rolled back: %w", err)
+	}
+	fmt.Println(scenarioID)
+	return nil
+}
+
+func validationError(v *validator.Validator) error {
+	keys := make([]string, 0, len(v.Errors))
+	for key := range v.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", key, v.Errors[key])
+	}
+	return fmt.Errorf("bundle failed validation")
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
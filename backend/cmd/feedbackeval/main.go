@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/evaluation"
+	"github.com/berberapan/info-eval/internal/feedback"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/validator"
+	_ "github.com/lib/pq"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  feedbackeval run -dataset=FILE [-out=FILE] [-llm-provider=NAME] [-free-text-template=SPEC] [-rubric-template=SPEC]")
+	fmt.Fprintln(os.Stderr, "  feedbackeval diff [-json] [-fail-on-regression] BASE_RUN HEAD_RUN")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "A template SPEC is \"default\", \"latest\", a stored version number or a file with a template body.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "run":
+		err = runEval(os.Args[2:])
+	case "diff":
+		err = runDiff(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	datasetPath := fs.String("dataset", "", "Golden dataset file")
+	out := fs.String("out", "", "Output file for the run (default stdout)")
+	dsn := fs.String("db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN, needed for stored template versions")
+	freeTextSpec := fs.String("free-text-template", "default", "Template for free-text feedback")
+	rubricSpec := fs.String("rubric-template", "default", "Template for rubric assessments")
+	var llmCfg llm.Config
+	var limits llm.Limits
+	fs.StringVar(&llmCfg.Provider, "llm-provider", "gemini", "LLM provider (gemini|openai|fake)")
+	fs.StringVar(&llmCfg.Model, "llm-model", "", "LLM model name (defaults to the provider's default)")
+	fs.StringVar(&llmCfg.BaseURL, "llm-base-url", "", "Base URL of an OpenAI-compatible API")
+	fs.StringVar(&llmCfg.APIKey, "llm-api-key", os.Getenv("LLM_API_KEY"), "API key for the LLM provider")
+	fs.IntVar(&limits.Concurrency, "llm-concurrency", 4, "Max concurrent requests to the LLM provider")
+	fs.IntVar(&limits.RequestsPerMinute, "llm-rpm", 60, "Max requests per minute to the LLM provider (0 for no limit)")
+	fs.DurationVar(&limits.Timeout, "llm-timeout", 60*time.Second, "Timeout for a single LLM request")
+	fs.Parse(args)
+
+	if *datasetPath == "" {
+		return errors.New("-dataset is required")
+	}
+	dataset, err := readDataset(*datasetPath)
+	if err != nil {
+		return err
+	}
+
+	provider, err := llm.New(llmCfg)
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return errors.New("an LLM provider is required")
+	}
+
+	var db *sql.DB
+	openModels := func() (*data.Models, error) {
+		if db == nil {
+			if *dsn == "" {
+				return nil, errors.New("stored template versions need -db-dsn")
+			}
+			if db, err = openDB(*dsn); err != nil {
+				return nil, err
+			}
+		}
+		models := data.NewModels(db)
+		return &models, nil
+	}
+	templates := make(map[data.PromptKind]*data.PromptTemplate)
+	labels := make(map[data.PromptKind]string)
+	for kind, spec := range map[data.PromptKind]string{data.PromptFreeText: *freeTextSpec, data.PromptRubric: *rubricSpec} {
+		templates[kind], labels[kind], err = loadTemplate(kind, spec, openModels)
+		if err != nil {
+			return fmt.Errorf("%s template: %w", kind, err)
+		}
+	}
+	if db != nil {
+		db.Close()
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	generator := &feedback.Generator{LLM: llm.NewLimited(provider, limits), Logger: logger}
+	run := &evaluation.Run{Provider: provider.Name(), Templates: labels, StartedAt: time.Now().UTC()}
+	logger.Info("evaluating feedback", "cases", len(dataset.Cases), "provider", run.Provider, "templates", labels)
+	run.Cases = evaluation.Evaluate(context.Background(), generator, templates, dataset)
+	run.Duration = time.Since(run.StartedAt).Round(time.Millisecond).String()
+
+	printSummary(os.Stderr, run.Summary())
+	return writeJSON(*out, run)
+}
+
+func loadTemplate(kind data.PromptKind, spec string, openModels func() (*data.Models, error)) (*data.PromptTemplate, string, error) {
+	if spec == "default" {
+		return data.DefaultPromptTemplates[kind], "default", nil
+	}
+	version, err := strconv.ParseInt(spec, 10, 32)
+	if spec == "latest" || err == nil {
+		models, err := openModels()
+		if err != nil {
+			return nil, "", err
+		}
+		var template *data.PromptTemplate
+		if spec == "latest" {
+			template, err = models.PromptTemplates.GetLatest(kind)
+			if errors.Is(err, data.ErrRecordNotFound) {
+				return data.DefaultPromptTemplates[kind], "default", nil
+			}
+		} else {
+			template, err = models.PromptTemplates.GetVersion(kind, int32(version))
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return template, fmt.Sprintf("v%d", template.Version), nil
+	}
+
+	body, err := os.ReadFile(spec)
+	if err != nil {
+		return nil, "", err
+	}
+	template := &data.PromptTemplate{Kind: kind, Body: string(body)}
+	v := validator.New()
+	if data.ValidatePromptTemplate(v, template); !v.Valid() {
+		return nil, "", validationError(v)
+	}
+	return template, "file:" + spec, nil
+}
+
+func readDataset(path string) (*evaluation.Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	var dataset evaluation.Dataset
+	if err := decoder.Decode(&dataset); err != nil {
+		return nil, fmt.Errorf("failed to decode dataset: %w", err)
+	}
+	v := validator.New()
+	if evaluation.ValidateDataset(v, &dataset); !v.Valid() {
+		return nil, validationError(v)
+	}
+	return &dataset, nil
+}
+
+func readRun(path string) (*evaluation.Run, error) {
+	js, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run evaluation.Run
+	if err := json.Unmarshal(js, &run); err != nil {
+		return nil, fmt.Errorf("failed to decode run %s: %w", path, err)
+	}
+	return &run, nil
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the comparison as JSON")
+	failOnRegression := fs.Bool("fail-on-regression", false, "Exit with an error when head regresses on any check")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+		os.Exit(2)
+	}
+	base, err := readRun(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	head, err := readRun(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	comparison := evaluation.Diff(base, head)
+	if *asJSON {
+		err = writeJSON("", comparison)
+	} else {
+		printComparison(os.Stdout, base, head, comparison)
+	}
+	if err != nil {
+		return err
+	}
+	if *failOnRegression && len(comparison.Regressions) > 0 {
+		return fmt.Errorf("%d regressions", len(comparison.Regressions))
+	}
+	return nil
+}
+
+func checkNames(summaries ...evaluation.Summary) []string {
+	var names []string
+	for _, s := range summaries {
+		for name := range s.Checks {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func ratio(passed, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d (%.0f%%)", passed, total, 100*float64(passed)/float64(total))
+}
+
+func printSummary(w io.Writer, s evaluation.Summary) {
+	fmt.Fprintf(w, "%-20s %s\n", "cases passed", ratio(s.Passed, s.Cases))
+	fmt.Fprintf(w, "%-20s %d\n", "errors", s.Errors)
+	for _, name := range checkNames(s) {
+		fmt.Fprintf(w, "%-20s %s\n", name, ratio(s.Checks[name].Passed, s.Checks[name].Total))
+	}
+}
+
+func printComparison(w io.Writer, base, head *evaluation.Run, c *evaluation.Comparison) {
+	fmt.Fprintf(w, "%-20s %-24s %s\n", "", "base", "head")
+	fmt.Fprintf(w, "%-20s %-24s %s\n", "provider", base.Provider, head.Provider)
+	for _, kind := range []data.PromptKind{data.PromptFreeText, data.PromptRubric} {
+		fmt.Fprintf(w, "%-20s %-24s %s\n", string(kind)+" template", base.Templates[kind], head.Templates[kind])
+	}
+	fmt.Fprintf(w, "%-20s %-24s %s\n", "cases passed", ratio(c.Base.Passed, c.Base.Cases), ratio(c.Head.Passed, c.Head.Cases))
+	fmt.Fprintf(w, "%-20s %-24d %d\n", "errors", c.Base.Errors, c.Head.Errors)
+	for _, name := range checkNames(c.Base, c.Head) {
+		fmt.Fprintf(w, "%-20s %-24s %s\n", name, ratio(c.Base.Checks[name].Passed, c.Base.Checks[name].Total), ratio(c.Head.Checks[name].Passed, c.Head.Checks[name].Total))
+	}
+	for _, section := range []struct {
+		title   string
+		changes []evaluation.Change
+	}{{"Regressions", c.Regressions}, {"Improvements", c.Improvements}} {
+		fmt.Fprintf(w, "\n%s (%d)\n", section.title, len(section.changes))
+		for _, change := range section.changes {
+			fmt.Fprintf(w, "  %s %s\n    base: %s\n    head: %s\n", change.CaseID, change.Check, change.Base, change.Head)
+		}
+	}
+	if len(c.Added) > 0 {
+		fmt.Fprintf(w, "\nOnly in head: %v\n", c.Added)
+	}
+	if len(c.Removed) > 0 {
+		fmt.Fprintf(w, "\nOnly in base: %v\n", c.Removed)
+	}
+}
+
+func validationError(v *validator.Validator) error {
+	keys := make([]string, 0, len(v.Errors))
+	for key := range v.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", key, v.Errors[key])
+	}
+	return errors.New("validation failed")
+}
+
+func writeJSON(path string, value any) error {
+	js, err := json.MarshalIndent(value, "", "\t")
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+	if path == "" {
+		_, err = os.Stdout.Write(js)
+		return err
+	}
+	return os.WriteFile(path, js, 0o644)
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
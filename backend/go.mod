@@ -12,6 +12,7 @@ require (
 require (
 	github.com/google/generative-ai-go v0.20.1
 	github.com/pascaldekloe/jwt v1.12.0
+	golang.org/x/time v0.11.0
 	google.golang.org/api v0.233.0
 )
 
@@ -39,7 +40,6 @@ require (
 	golang.org/x/sync v0.14.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.25.0 // indirect
-	golang.org/x/time v0.11.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20250218202821-56aae31c358a // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250505200425-f936aa4a68b2 // indirect
 	google.golang.org/grpc v1.72.0 // indirect
@@ -0,0 +1,405 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/storage"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+// Version 2 added files. Version 1 bundles are still accepted.
+const BundleFormatVersion = 2
+
+const uploadedMediaPrefix = "/v1/media/"
+
+type ScenarioBundle struct {
+	FormatVersion int            `json:"format_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Scenario      BundleScenario `json:"scenario"`
+	Files         []BundleFile   `json:"files,omitempty"`
+}
+
+// A BundleFile carries an uploaded media file, so the bundle can be imported
+// on an instance that doesn't have it. Media refer to it by its hash.
+type BundleFile struct {
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+type BundleScenario struct {
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Difficulty  int16            `json:"difficulty"`
+	Language    Language         `json:"language,omitempty"`
+	Exercises   []BundleExercise `json:"exercises"`
+}
+
+type BundleExercise struct {
+	Info      string           `json:"info"`
+	Media     []BundleMedia    `json:"media"`
+	Questions []BundleQuestion `json:"questions"`
+}
+
+type BundleMedia struct {
+	MediaURL  string    `json:"media_url,omitempty"`
+	File      string    `json:"file,omitempty"`
+	MediaType MediaType `json:"media_type"`
+}
+
+type BundleQuestion struct {
+	Type           ExerciseType   `json:"type"`
+	Question       string         `json:"question"`
+	PromptGuidance string         `json:"prompt_guidance,omitempty"`
+	Rubric         *Rubric        `json:"rubric,omitempty"`
+	Options        []BundleOption `json:"options"`
+}
+
+type BundleOption struct {
+	OptionText string `json:"option_text"`
+	IsCorrect  bool   `json:"is_correct"`
+	Feedback   string `json:"feedback"`
+	MatchText  string `json:"match_text,omitempty"`
+}
+
+func NewScenarioBundle(scenario *Scenario) *ScenarioBundle {
+	b := &ScenarioBundle{
+		FormatVersion: BundleFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		Scenario: BundleScenario{
+			Title:       scenario.Title,
+			Description: scenario.Description,
+			Difficulty:  scenario.Difficulty,
+			Language:    scenario.Language,
+			Exercises:   make([]BundleExercise, 0, len(scenario.Exercises)),
+		},
+	}
+	for _, e := range scenario.Exercises {
+		be := BundleExercise{
+			Info:      e.Info,
+			Media:     make([]BundleMedia, 0, len(e.Media)),
+			Questions: make([]BundleQuestion, 0, len(e.Questions)),
+		}
+		for _, m := range e.Media {
+			be.Media = append(be.Media, BundleMedia{MediaURL: m.MediaURL, MediaType: m.MediaType})
+		}
+		for _, q := range e.Questions {
+			bq := BundleQuestion{
+				Type:           q.ExerciseType,
+				Question:       q.Question,
+				PromptGuidance: q.PromptGuidance.String,
+				Rubric:         q.Rubric,
+				Options:        make([]BundleOption, 0, len(q.Options)),
+			}
+			for _, o := range q.Options {
+				bq.Options = append(bq.Options, BundleOption{OptionText: o.OptionText, IsCorrect: o.IsCorrect, Feedback: o.Feedback, MatchText: o.MatchText})
+			}
+			be.Questions = append(be.Questions, bq)
+		}
+		b.Scenario.Exercises = append(b.Scenario.Exercises, be)
+	}
+	return b
+}
+
+func validateNested(v *validator.Validator, prefix string, validate func(*validator.Validator)) {
+	nested := validator.New()
+	validate(nested)
+	for key, message := range nested.Errors {
+		v.AddError(prefix+"."+key, message)
+	}
+}
+
+func ValidateScenarioBundle(v *validator.Validator, b *ScenarioBundle) {
+	v.Check(b.FormatVersion >= 1 && b.FormatVersion <= BundleFormatVersion, "format_version", fmt.Sprintf("must be between 1 and %d", BundleFormatVersion))
+
+	files := make(map[string]MediaType)
+	for i, f := range b.Files {
+		key := fmt.Sprintf("files[%d]", i)
+		sum := sha256.Sum256(f.Data)
+		v.Check(hex.EncodeToString(sum[:]) == f.SHA256, key+".sha256", "must match the file data")
+		mediaType, ok := MediaTypeForContentType(f.ContentType)
+		v.Check(ok, key+".content_type", "must be an image, video or audio type")
+		files[f.SHA256] = mediaType
+	}
+
+	validateNested(v, "scenario", func(v *validator.Validator) {
+		ValidateScenario(v, &Scenario{Title: b.Scenario.Title, Description: b.Scenario.Description, Difficulty: b.Scenario.Difficulty, Status: ScenarioDraft, Language: b.Scenario.Language.OrDefault()})
+	})
+	for i, e := range b.Scenario.Exercises {
+		prefix := fmt.Sprintf("scenario.exercises[%d]", i)
+		validateNested(v, prefix, func(v *validator.Validator) {
+			ValidateExercise(v, &Exercise{Info: e.Info})
+		})
+		for j, m := range e.Media {
+			validateNested(v, fmt.Sprintf("%s.media[%d]", prefix, j), func(v *validator.Validator) {
+				if m.File == "" {
+					ValidateExerciseMedia(v, &ExerciseMedia{MediaURL: m.MediaURL, MediaType: m.MediaType})
+					return
+				}
+				v.Check(m.MediaURL == "", "media_url", "must be empty when the file is included")
+				mediaType, ok := files[m.File]
+				v.Check(ok, "file", "must be one of the bundle's files")
+				v.Check(!ok || mediaType == m.MediaType, "media_type", "must match the file's content type")
+			})
+		}
+		for j, q := range e.Questions {
+			validateNested(v, fmt.Sprintf("%s.questions[%d]", prefix, j), func(v *validator.Validator) {
+				ValidateBundleQuestion(v, &q)
+			})
+		}
+	}
+}
+
+func ValidateBundleQuestion(v *validator.Validator, q *BundleQuestion) {
+	ValidateExerciseQuestion(v, &ExerciseQuestion{
+		ExerciseType:   q.Type,
+		Question:       q.Question,
+		PromptGuidance: sql.NullString{String: q.PromptGuidance, Valid: q.PromptGuidance != ""},
+		Rubric:         q.Rubric,
+	})
+	v.Check(q.Type.HasOptions() || len(q.Options) == 0, "options", fmt.Sprintf("%s questions can't have options", q.Type))
+	for k, o := range q.Options {
+		validateNested(v, fmt.Sprintf("options[%d]", k), func(v *validator.Validator) {
+			option := &QuestionOption{OptionText: o.OptionText, IsCorrect: o.IsCorrect, Feedback: o.Feedback, MatchText: o.MatchText}
+			ValidateQuestionOption(v, option)
+			if q.Type.HasOptions() {
+				ValidateOptionForType(v, q.Type, option)
+			}
+		})
+	}
+}
+
+type BundleModel struct {
+	DB *sql.DB
+}
+
+// PackFiles puts the uploaded files the bundle's media point at into the
+// bundle, replacing their URLs, which only work on this instance.
+func (bm *BundleModel) PackFiles(ctx context.Context, b *ScenarioBundle, store storage.Storage) error {
+	objects := MediaObjectModel{DB: bm.DB}
+	packed := make(map[string]bool)
+	for i := range b.Scenario.Exercises {
+		for j := range b.Scenario.Exercises[i].Media {
+			m := &b.Scenario.Exercises[i].Media[j]
+			idStr, ok := strings.CutPrefix(m.MediaURL, uploadedMediaPrefix)
+			if !ok {
+				continue
+			}
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				return fmt.Errorf("exercise %d media %d: %w", i, j, err)
+			}
+			object, err := objects.Get(id)
+			if err != nil {
+				return fmt.Errorf("exercise %d media %d: %w", i, j, err)
+			}
+			if !packed[object.SHA256] {
+				f, err := store.Open(ctx, object.SHA256)
+				if err != nil {
+					return fmt.Errorf("exercise %d media %d: %w", i, j, err)
+				}
+				data, err := io.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return fmt.Errorf("exercise %d media %d: %w", i, j, err)
+				}
+				b.Files = append(b.Files, BundleFile{SHA256: object.SHA256, ContentType: object.ContentType, Data: data})
+				packed[object.SHA256] = true
+			}
+			m.MediaURL = ""
+			m.File = object.SHA256
+		}
+	}
+	return nil
+}
+
+// MediaLimits caps the size of media files by type, in bytes.
+type MediaLimits map[MediaType]int64
+
+// ValidateBundleFiles holds bundle files to the same rules as uploads: the
+// type is sniffed from the data rather than trusted, and has to be within
+// the size limit for its kind of media.
+func ValidateBundleFiles(v *validator.Validator, files []BundleFile, limits MediaLimits) {
+	for i, f := range files {
+		key := fmt.Sprintf("files[%d]", i)
+		contentType := http.DetectContentType(f.Data)
+		mediaType, ok := MediaTypeForContentType(contentType)
+		if !ok {
+			v.AddError(key+".data", fmt.Sprintf("unsupported content type %s", contentType))
+			continue
+		}
+		v.Check(f.ContentType == contentType, key+".content_type", "must match the file data")
+		v.Check(int64(len(f.Data)) <= limits[mediaType], key+".data", fmt.Sprintf("can't be larger than %d bytes", limits[mediaType]))
+	}
+}
+
+// CheckUploadedMedia reports media that point at an uploaded file this
+// instance doesn't have, without the file being included in the bundle.
+func (bm *BundleModel) CheckUploadedMedia(v *validator.Validator, b *ScenarioBundle) error {
+	objects := MediaObjectModel{DB: bm.DB}
+	for i, e := range b.Scenario.Exercises {
+		for j, m := range e.Media {
+			idStr, ok := strings.CutPrefix(m.MediaURL, uploadedMediaPrefix)
+			if m.File != "" || !ok {
+				continue
+			}
+			key := fmt.Sprintf("scenario.exercises[%d].media[%d].media_url", i, j)
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				v.AddError(key, "must refer to an uploaded file on this server or include the file")
+				continue
+			}
+			_, err = objects.Get(id)
+			switch {
+			case errors.Is(err, ErrRecordNotFound):
+				v.AddError(key, "must refer to an uploaded file on this server or include the file")
+			case err != nil:
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insertBundleFile adds the file's media object in tx and stores the data if
+// the object is new. It returns the object's URL and whether the data was
+// written to store, so it can be removed again if the import rolls back.
+func insertBundleFile(ctx context.Context, tx *sql.Tx, store storage.Storage, f *BundleFile, uploadedBy uuid.NullUUID) (string, bool, error) {
+	mediaType, _ := MediaTypeForContentType(f.ContentType)
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+	INSERT INTO media_objects (sha256, content_type, media_type, size_bytes, uploaded_by)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (sha256) DO NOTHING
+	RETURNING id`, f.SHA256, f.ContentType, mediaType, len(f.Data), uploadedBy).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = tx.QueryRowContext(ctx, `SELECT id FROM media_objects WHERE sha256 = $1`, f.SHA256).Scan(&id)
+		return uploadedMediaPrefix + id.String(), false, err
+	case err != nil:
+		return "", false, err
+	}
+	existing, err := store.Open(ctx, f.SHA256)
+	if err == nil {
+		existing.Close()
+		return uploadedMediaPrefix + id.String(), false, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return "", false, err
+	}
+	if err := store.Put(ctx, f.SHA256, bytes.NewReader(f.Data)); err != nil {
+		return "", false, err
+	}
+	return uploadedMediaPrefix + id.String(), true, nil
+}
+
+// Import creates the bundle's scenario in one transaction. The bundle's files
+// are stored as part of it and removed again if the import rolls back.
+func (bm *BundleModel) Import(b *ScenarioBundle, ownerID, clonedFrom uuid.NullUUID, store storage.Storage) (uuid.UUID, error) {
+	timeout := 15 * time.Second
+	if len(b.Files) > 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	tx, err := bm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	var stored []string
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		for _, key := range stored {
+			store.Delete(context.Background(), key)
+		}
+	}()
+	urls := make(map[string]string)
+	for i := range b.Files {
+		url, written, err := insertBundleFile(ctx, tx, store, &b.Files[i], ownerID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("file %d: %w", i, err)
+		}
+		if written {
+			stored = append(stored, b.Files[i].SHA256)
+		}
+		urls[b.Files[i].SHA256] = url
+	}
+
+	var scenarioID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+	INSERT INTO scenarios (title, description, difficulty, language, owner_id, cloned_from)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id`, b.Scenario.Title, b.Scenario.Description, b.Scenario.Difficulty, b.Scenario.Language.OrDefault(), ownerID, clonedFrom).Scan(&scenarioID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for i, e := range b.Scenario.Exercises {
+		var exerciseID uuid.UUID
+		err = tx.QueryRowContext(ctx, `
+		INSERT INTO exercises (scenario_id, info, "order")
+		VALUES ($1, $2, $3)
+		RETURNING id`, scenarioID, e.Info, i+1).Scan(&exerciseID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("exercise %d: %w", i, err)
+		}
+		for j, m := range e.Media {
+			mediaURL := m.MediaURL
+			if m.File != "" {
+				mediaURL = urls[m.File]
+			}
+			_, err = tx.ExecContext(ctx, `
+			INSERT INTO exercise_media (exercise_id, media_url, media_type)
+			VALUES ($1, $2, $3)`, exerciseID, mediaURL, m.MediaType)
+			if err != nil {
+				return uuid.Nil, fmt.Errorf("exercise %d media %d: %w", i, j, err)
+			}
+		}
+		for j, q := range e.Questions {
+			if _, err = insertBundleQuestion(ctx, tx, exerciseID, &q); err != nil {
+				return uuid.Nil, fmt.Errorf("exercise %d question %d: %w", i, j, err)
+			}
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	committed = true
+	return scenarioID, nil
+}
+
+// insertBundleQuestion appends the question and its options to the exercise.
+func insertBundleQuestion(ctx context.Context, tx *sql.Tx, exerciseID uuid.UUID, q *BundleQuestion) (uuid.UUID, error) {
+	var questionID uuid.UUID
+	err := tx.QueryRowContext(ctx, `
+	INSERT INTO exercise_questions (exercise_id, type, question, prompt_guidance, rubric, "order")
+	VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX("order"), 0) + 1 FROM exercise_questions WHERE exercise_id = $1))
+	RETURNING id`, exerciseID, q.Type, q.Question, sql.NullString{String: q.PromptGuidance, Valid: q.PromptGuidance != ""}, q.Rubric).Scan(&questionID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for k, o := range q.Options {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO exercise_question_options (exercise_question_id, option_text, is_correct, feedback, match_text, "order")
+		VALUES ($1, $2, $3, $4, $5, $6)`, questionID, o.OptionText, o.IsCorrect, o.Feedback, sql.NullString{String: o.MatchText, Valid: o.MatchText != ""}, k+1)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("option %d: %w", k, err)
+		}
+	}
+	return questionID, nil
+}
@@ -0,0 +1,200 @@
+package data
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+func TestNewScenarioBundle(t *testing.T) {
+	scenario := &Scenario{
+		ID:          uuid.New(),
+		Title:       "Källkritik",
+		Description: "Granska nyheter",
+		Difficulty:  2,
+		Exercises: []Exercise{
+			{
+				ID:    uuid.New(),
+				Info:  "Läs artikeln",
+				Media: []ExerciseMedia{{ID: uuid.New(), MediaURL: "https://example.com/a.png", MediaType: ImageType}},
+				Questions: []ExerciseQuestion{
+					{
+						ID:             uuid.New(),
+						ExerciseType:   MultipleChoiceType,
+						Question:       "Vem är avsändaren?",
+						PromptGuidance: sql.NullString{String: "Avsändaren är en myndighet", Valid: true},
+						Options: []QuestionOption{
+							{ID: uuid.New(), OptionText: "Myndighet", IsCorrect: true, Feedback: "Rätt"},
+							{ID: uuid.New(), OptionText: "Privatperson", Feedback: "Fel"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b := NewScenarioBundle(scenario)
+
+	assert.Equal(t, b.FormatVersion, BundleFormatVersion)
+	assert.Equal(t, b.Scenario.Title, "Källkritik")
+	assert.Equal(t, len(b.Scenario.Exercises), 1)
+	assert.Equal(t, b.Scenario.Exercises[0].Media[0].MediaType, ImageType)
+	q := b.Scenario.Exercises[0].Questions[0]
+	assert.Equal(t, q.PromptGuidance, "Avsändaren är en myndighet")
+	assert.Equal(t, len(q.Options), 2)
+	assert.Equal(t, q.Options[0].IsCorrect, true)
+	assert.Equal(t, q.Options[1].Feedback, "Fel")
+
+	v := validator.New()
+	ValidateScenarioBundle(v, b)
+	assert.Equal(t, v.Valid(), true)
+}
+
+// pngSHA256 is the hash of the bytes "png".
+const pngSHA256 = "8f8cbb7dcf46e0bc7d53265749a6c17d116093a6ba95e442764060c76fd4a86c"
+
+func TestValidateScenarioBundle(t *testing.T) {
+	tests := []struct {
+		name     string
+		bundle   ScenarioBundle
+		wantKeys []string
+	}{
+		{
+			name: "Unsupported format version",
+			bundle: ScenarioBundle{
+				FormatVersion: 99,
+				Scenario:      BundleScenario{Title: "T", Difficulty: 1},
+			},
+			wantKeys: []string{"format_version"},
+		},
+		{
+			name: "Unsupported language",
+			bundle: ScenarioBundle{
+				FormatVersion: BundleFormatVersion,
+				Scenario:      BundleScenario{Title: "T", Difficulty: 1, Language: "fi"},
+			},
+			wantKeys: []string{"scenario.language"},
+		},
+		{
+			name: "Nested errors",
+			bundle: ScenarioBundle{
+				FormatVersion: BundleFormatVersion,
+				Scenario: BundleScenario{
+					Difficulty: 1,
+					Exercises: []BundleExercise{
+						{
+							Info: "Info",
+							Questions: []BundleQuestion{
+								{Type: FreeTextType, Question: "Q", Options: []BundleOption{{OptionText: "A"}}},
+								{Type: "essay", Question: "Q"},
+							},
+						},
+					},
+				},
+			},
+			wantKeys: []string{
+				"scenario.title",
+				"scenario.exercises[0].questions[0].options",
+				"scenario.exercises[0].questions[1].type",
+			},
+		},
+		{
+			name: "Match texts",
+			bundle: ScenarioBundle{
+				FormatVersion: BundleFormatVersion,
+				Scenario: BundleScenario{
+					Title:      "T",
+					Difficulty: 1,
+					Exercises: []BundleExercise{
+						{
+							Info: "Info",
+							Questions: []BundleQuestion{
+								{Type: MatchingType, Question: "Q", Options: []BundleOption{{OptionText: "A", MatchText: "SVT"}, {OptionText: "B"}}},
+								{Type: MultiSelectType, Question: "Q", Options: []BundleOption{{OptionText: "A", MatchText: "SVT"}}},
+							},
+						},
+					},
+				},
+			},
+			wantKeys: []string{
+				"scenario.exercises[0].questions[0].options[1].match_text",
+				"scenario.exercises[0].questions[1].options[0].match_text",
+			},
+		},
+		{
+			name: "Version 1",
+			bundle: ScenarioBundle{
+				FormatVersion: 1,
+				Scenario:      BundleScenario{Title: "T", Difficulty: 1},
+			},
+		},
+		{
+			name: "Files",
+			bundle: ScenarioBundle{
+				FormatVersion: BundleFormatVersion,
+				Scenario: BundleScenario{
+					Title:      "T",
+					Difficulty: 1,
+					Exercises: []BundleExercise{
+						{
+							Info: "Info",
+							Media: []BundleMedia{
+								{File: pngSHA256, MediaType: ImageType},
+								{File: pngSHA256, MediaType: VideoType},
+								{File: pngSHA256, MediaURL: "/v1/media/a", MediaType: ImageType},
+								{File: "missing", MediaType: ImageType},
+							},
+						},
+					},
+				},
+				Files: []BundleFile{
+					{SHA256: pngSHA256, ContentType: "image/png", Data: []byte("png")},
+					{SHA256: strings.Repeat("0", 64), ContentType: "text/html", Data: []byte("html")},
+				},
+			},
+			wantKeys: []string{
+				"files[1].sha256",
+				"files[1].content_type",
+				"scenario.exercises[0].media[1].media_type",
+				"scenario.exercises[0].media[2].media_url",
+				"scenario.exercises[0].media[3].file",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateScenarioBundle(v, &tt.bundle)
+			assert.Equal(t, len(v.Errors), len(tt.wantKeys))
+			for _, key := range tt.wantKeys {
+				_, ok := v.Errors[key]
+				assert.Equal(t, ok, true)
+			}
+		})
+	}
+}
+
+func TestValidateBundleFiles(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)
+	limits := MediaLimits{ImageType: 20, VideoType: 100, AudioType: 100}
+	files := []BundleFile{
+		{ContentType: "image/png", Data: png[:16]},
+		{ContentType: "image/gif", Data: png[:16]},
+		{ContentType: "image/png", Data: png},
+		{ContentType: "image/png", Data: []byte("<html><body></body></html>")},
+	}
+
+	v := validator.New()
+	ValidateBundleFiles(v, files, limits)
+	wantKeys := []string{"files[1].content_type", "files[2].data", "files[3].data"}
+	assert.Equal(t, len(v.Errors), len(wantKeys))
+	for _, key := range wantKeys {
+		_, ok := v.Errors[key]
+		assert.Equal(t, ok, true)
+	}
+}
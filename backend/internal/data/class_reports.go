@@ -0,0 +1,157 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+// The model refers to answers by their number in the prompt. Answers is only
+// used while checking its reply; what gets stored and shown is the count, so
+// a theme can't be traced back to a student.
+type ClassReportTheme struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Answers     []int    `json:"answers,omitempty"`
+	Count       int      `json:"count"`
+	Examples    []string `json:"examples"`
+}
+
+type ClassReportMisconception struct {
+	Description string   `json:"description"`
+	Suggestion  string   `json:"suggestion"`
+	Answers     []int    `json:"answers,omitempty"`
+	Count       int      `json:"count"`
+	Examples    []string `json:"examples"`
+}
+
+type ClassReport struct {
+	Themes         []ClassReportTheme         `json:"themes"`
+	Misconceptions []ClassReportMisconception `json:"misconceptions"`
+}
+
+func (r *ClassReport) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *ClassReport) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("class report must be scanned from JSON")
+	}
+	return json.Unmarshal(b, r)
+}
+
+func ValidateClassReport(v *validator.Validator, r *ClassReport, answerCount int) {
+	v.Check(len(r.Themes) >= 1, "themes", "must contain at least one theme")
+	v.Check(len(r.Themes) <= 5, "themes", "can't contain more than 5 themes")
+	v.Check(len(r.Misconceptions) <= 5, "misconceptions", "can't contain more than 5 misconceptions")
+	for i, t := range r.Themes {
+		key := fmt.Sprintf("themes[%d]", i)
+		v.Check(t.Name != "", key+".name", "must be provided")
+		v.Check(len(t.Name) <= 100, key+".name", "can't exceed 100 chars")
+		validateReportText(v, key+".description", t.Description)
+		validateReportAnswers(v, key, t.Answers, t.Examples, answerCount)
+	}
+	for i, m := range r.Misconceptions {
+		key := fmt.Sprintf("misconceptions[%d]", i)
+		validateReportText(v, key+".description", m.Description)
+		validateReportText(v, key+".suggestion", m.Suggestion)
+		validateReportAnswers(v, key, m.Answers, m.Examples, answerCount)
+	}
+}
+
+func validateReportText(v *validator.Validator, key, text string) {
+	v.Check(text != "", key, "must be provided")
+	v.Check(len(text) <= 500, key, "can't exceed 500 chars")
+}
+
+func validateReportAnswers(v *validator.Validator, key string, answers []int, examples []string, answerCount int) {
+	v.Check(len(answers) >= 1, key+".answers", "must contain at least one answer number")
+	v.Check(validator.Unique(answers), key+".answers", "must not contain duplicate answer numbers")
+	for _, n := range answers {
+		if n < 1 || n > answerCount {
+			v.AddError(key+".answers", fmt.Sprintf("must be answer numbers between 1 and %d", answerCount))
+		}
+	}
+	v.Check(len(examples) <= 3, key+".examples", "can't contain more than 3 examples")
+	for _, e := range examples {
+		v.Check(e != "", key+".examples", "must not contain empty examples")
+		v.Check(len(e) <= 300, key+".examples", "can't contain examples longer than 300 chars")
+	}
+}
+
+// A stored report stays valid until the set of answers it was made from
+// changes, which shows up as a new count or a newer submission.
+type StoredClassReport struct {
+	ScenarioSessionID uuid.UUID     `json:"scenario_session_id"`
+	QuestionID        uuid.UUID     `json:"question_id"`
+	Report            *ClassReport  `json:"report"`
+	AnswerCount       int           `json:"answer_count"`
+	LastSubmittedAt   time.Time     `json:"last_submitted_at"`
+	PromptTemplateID  uuid.NullUUID `json:"-"`
+	CreatedAt         time.Time     `json:"generated_at"`
+}
+
+func (r *StoredClassReport) Covers(answerCount int, lastSubmittedAt time.Time) bool {
+	return r.AnswerCount == answerCount && r.LastSubmittedAt.Equal(lastSubmittedAt)
+}
+
+type ClassReportModel struct {
+	DB *sql.DB
+}
+
+func (cm *ClassReportModel) Get(scenarioSessionID, questionID uuid.UUID) (*StoredClassReport, error) {
+	query := `
+	SELECT scenario_session_id, question_id, report, answer_count, last_submitted_at, prompt_template_id, created_at
+	FROM class_reports
+	WHERE scenario_session_id = $1 AND question_id = $2`
+	var r StoredClassReport
+	r.Report = &ClassReport{}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := cm.DB.QueryRowContext(ctx, query, scenarioSessionID, questionID).Scan(
+		&r.ScenarioSessionID,
+		&r.QuestionID,
+		r.Report,
+		&r.AnswerCount,
+		&r.LastSubmittedAt,
+		&r.PromptTemplateID,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+func (cm *ClassReportModel) Upsert(r *StoredClassReport) error {
+	query := `
+	INSERT INTO class_reports (scenario_session_id, question_id, report, answer_count, last_submitted_at, prompt_template_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (scenario_session_id, question_id) DO UPDATE
+	SET report = EXCLUDED.report,
+		answer_count = EXCLUDED.answer_count,
+		last_submitted_at = EXCLUDED.last_submitted_at,
+		prompt_template_id = EXCLUDED.prompt_template_id,
+		created_at = now()
+	RETURNING created_at`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return cm.DB.QueryRowContext(ctx, query, r.ScenarioSessionID, r.QuestionID, r.Report, r.AnswerCount, r.LastSubmittedAt, r.PromptTemplateID).Scan(&r.CreatedAt)
+}
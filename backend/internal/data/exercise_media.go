@@ -3,8 +3,11 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"net/url"
 	"time"
 
+	"github.com/berberapan/info-eval/internal/validator"
 	"github.com/google/uuid"
 )
 
@@ -16,23 +19,36 @@ const (
 	AudioType MediaType = "audio"
 )
 
+var MediaTypes = []MediaType{ImageType, VideoType, AudioType}
+
 type ExerciseMedia struct {
-	ID        uuid.UUID `json:"id"`
-	MediaURL  string    `json:"media_url"`
-	MediaType MediaType `json:"media_type"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         uuid.UUID `json:"id"`
+	ExerciseID uuid.UUID `json:"exercise_id"`
+	MediaURL   string    `json:"media_url"`
+	MediaType  MediaType `json:"media_type"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 type ExerciseMediaModel struct {
 	DB *sql.DB
 }
 
+func ValidateExerciseMedia(v *validator.Validator, media *ExerciseMedia) {
+	v.Check(media.MediaURL != "", "media_url", "must be provided")
+	v.Check(len(media.MediaURL) <= 2_000, "media_url", "can't exceed 2000 chars")
+	u, err := url.Parse(media.MediaURL)
+	v.Check(err == nil && (u.IsAbs() || u.Path != "" && u.Path[0] == '/'), "media_url", "must be an absolute URL or path")
+
+	v.Check(validator.PermittedValues(media.MediaType, MediaTypes...), "media_type", "must be image, video or audio")
+}
+
 func (em *ExerciseMediaModel) GetByExerciseID(exerciseID uuid.UUID) ([]ExerciseMedia, error) {
 	query := `
 	SELECT id, media_url, media_type, created_at, updated_at
 	FROM exercise_media
-	WHERE exercise_id = $1`
+	WHERE exercise_id = $1
+	ORDER BY created_at`
 	var mediaLSlice []ExerciseMedia
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -43,6 +59,7 @@ func (em *ExerciseMediaModel) GetByExerciseID(exerciseID uuid.UUID) ([]ExerciseM
 	defer rows.Close()
 	for rows.Next() {
 		var media ExerciseMedia
+		media.ExerciseID = exerciseID
 		if err := rows.Scan(&media.ID, &media.MediaURL, &media.MediaType, &media.CreatedAt, &media.UpdatedAt); err != nil {
 			return nil, err
 		}
@@ -50,3 +67,77 @@ func (em *ExerciseMediaModel) GetByExerciseID(exerciseID uuid.UUID) ([]ExerciseM
 	}
 	return mediaLSlice, nil
 }
+
+func (em *ExerciseMediaModel) Get(id uuid.UUID) (*ExerciseMedia, error) {
+	query := `
+	SELECT id, exercise_id, media_url, media_type, created_at, updated_at
+	FROM exercise_media
+	WHERE id = $1`
+	var media ExerciseMedia
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := em.DB.QueryRowContext(ctx, query, id).Scan(
+		&media.ID, &media.ExerciseID, &media.MediaURL, &media.MediaType, &media.CreatedAt, &media.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &media, nil
+}
+
+func (em *ExerciseMediaModel) Insert(media *ExerciseMedia) error {
+	query := `
+	INSERT INTO exercise_media (exercise_id, media_url, media_type)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, updated_at`
+	args := []any{media.ExerciseID, media.MediaURL, media.MediaType}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return em.DB.QueryRowContext(ctx, query, args...).Scan(&media.ID, &media.CreatedAt, &media.UpdatedAt)
+}
+
+func (em *ExerciseMediaModel) Update(media *ExerciseMedia) error {
+	query := `
+	UPDATE exercise_media
+	SET media_url = $1, media_type = $2, updated_at = now()
+	WHERE id = $3
+	RETURNING updated_at`
+	args := []any{media.MediaURL, media.MediaType, media.ID}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := em.DB.QueryRowContext(ctx, query, args...).Scan(&media.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (em *ExerciseMediaModel) Delete(id uuid.UUID) error {
+	query := `
+	DELETE FROM exercise_media
+	WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := em.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
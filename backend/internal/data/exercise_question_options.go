@@ -3,16 +3,22 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/berberapan/info-eval/internal/validator"
 	"github.com/google/uuid"
 )
 
 type QuestionOption struct {
 	ID         uuid.UUID `json:"id"`
+	QuestionID uuid.UUID `json:"question_id"`
 	OptionText string    `json:"option_text"`
 	IsCorrect  bool      `json:"is_correct"`
 	Feedback   string    `json:"feedback"`
+	MatchText  string    `json:"match_text,omitempty"`
+	Order      int16     `json:"order"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -21,11 +27,30 @@ type QuestionOptionModel struct {
 	DB *sql.DB
 }
 
+func ValidateQuestionOption(v *validator.Validator, option *QuestionOption) {
+	v.Check(option.OptionText != "", "option_text", "must be provided")
+	v.Check(len(option.OptionText) <= 2_000, "option_text", "can't exceed 2000 chars")
+
+	v.Check(len(option.Feedback) <= 5_000, "feedback", "can't exceed 5000 chars")
+
+	v.Check(len(option.MatchText) <= 2_000, "match_text", "can't exceed 2000 chars")
+}
+
+func ValidateOptionForType(v *validator.Validator, t ExerciseType, option *QuestionOption) {
+	v.Check(t.HasOptions(), "type", fmt.Sprintf("%s questions can't have options", t))
+	if t == MatchingType {
+		v.Check(option.MatchText != "", "match_text", "must be provided for matching questions")
+	} else {
+		v.Check(option.MatchText == "", "match_text", "is only allowed for matching questions")
+	}
+}
+
 func (qm *QuestionOptionModel) GetByQuestionID(questionID uuid.UUID) ([]QuestionOption, error) {
 	query := `
-	SELECT id, option_text, is_correct, feedback, created_at, updated_at
+	SELECT id, option_text, is_correct, COALESCE(feedback, ''), COALESCE(match_text, ''), "order", created_at, updated_at
 	FROM exercise_question_options
-	WHERE exercise_question_id = $1`
+	WHERE exercise_question_id = $1
+	ORDER BY "order", created_at`
 	var questionOptionSlice []QuestionOption
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -36,10 +61,89 @@ func (qm *QuestionOptionModel) GetByQuestionID(questionID uuid.UUID) ([]Question
 	defer rows.Close()
 	for rows.Next() {
 		var option QuestionOption
-		if err := rows.Scan(&option.ID, &option.OptionText, &option.IsCorrect, &option.Feedback, &option.CreatedAt, &option.UpdatedAt); err != nil {
+		option.QuestionID = questionID
+		if err := rows.Scan(&option.ID, &option.OptionText, &option.IsCorrect, &option.Feedback, &option.MatchText, &option.Order, &option.CreatedAt, &option.UpdatedAt); err != nil {
 			return nil, err
 		}
 		questionOptionSlice = append(questionOptionSlice, option)
 	}
 	return questionOptionSlice, nil
 }
+
+func (qm *QuestionOptionModel) Get(id uuid.UUID) (*QuestionOption, error) {
+	query := `
+	SELECT id, exercise_question_id, option_text, is_correct, COALESCE(feedback, ''), COALESCE(match_text, ''), "order", created_at, updated_at
+	FROM exercise_question_options
+	WHERE id = $1`
+	var option QuestionOption
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := qm.DB.QueryRowContext(ctx, query, id).Scan(
+		&option.ID, &option.QuestionID, &option.OptionText, &option.IsCorrect, &option.Feedback, &option.MatchText, &option.Order, &option.CreatedAt, &option.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &option, nil
+}
+
+func (qm *QuestionOptionModel) Insert(option *QuestionOption) error {
+	query := `
+	INSERT INTO exercise_question_options (exercise_question_id, option_text, is_correct, feedback, match_text, "order")
+	VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX("order"), 0) + 1 FROM exercise_question_options WHERE exercise_question_id = $1))
+	RETURNING id, "order", created_at, updated_at`
+	args := []any{option.QuestionID, option.OptionText, option.IsCorrect, option.Feedback, sql.NullString{String: option.MatchText, Valid: option.MatchText != ""}}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return qm.DB.QueryRowContext(ctx, query, args...).Scan(&option.ID, &option.Order, &option.CreatedAt, &option.UpdatedAt)
+}
+
+func (qm *QuestionOptionModel) Update(option *QuestionOption) error {
+	query := `
+	UPDATE exercise_question_options
+	SET option_text = $1, is_correct = $2, feedback = $3, match_text = $4, updated_at = now()
+	WHERE id = $5
+	RETURNING updated_at`
+	args := []any{option.OptionText, option.IsCorrect, option.Feedback, sql.NullString{String: option.MatchText, Valid: option.MatchText != ""}, option.ID}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := qm.DB.QueryRowContext(ctx, query, args...).Scan(&option.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (qm *QuestionOptionModel) Delete(id uuid.UUID) error {
+	query := `
+	DELETE FROM exercise_question_options
+	WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := qm.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (qm *QuestionOptionModel) Reorder(questionID uuid.UUID, ids []uuid.UUID) error {
+	return reorder(qm.DB, "exercise_question_options", "exercise_question_id", questionID, ids)
+}
@@ -3,8 +3,10 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/berberapan/info-eval/internal/validator"
 	"github.com/google/uuid"
 )
 
@@ -14,15 +16,28 @@ const (
 	FreeTextType       ExerciseType = "free_text"
 	TrueFalseType      ExerciseType = "true_false"
 	MultipleChoiceType ExerciseType = "multiple_choice"
+	MultiSelectType    ExerciseType = "multi_select"
+	OrderingType       ExerciseType = "ordering"
+	MatchingType       ExerciseType = "matching"
+	LikertType         ExerciseType = "likert"
 )
 
+var ExerciseTypes = []ExerciseType{FreeTextType, TrueFalseType, MultipleChoiceType, MultiSelectType, OrderingType, MatchingType, LikertType}
+
+func (t ExerciseType) HasOptions() bool {
+	return t != FreeTextType
+}
+
 type ExerciseQuestion struct {
 	ID             uuid.UUID        `json:"id"`
 	ExerciseID     uuid.UUID        `json:"exercise_id"`
 	ExerciseType   ExerciseType     `json:"type"`
 	Question       string           `json:"question"`
+	Order          int16            `json:"order"`
 	Options        []QuestionOption `json:"options"`
 	PromptGuidance sql.NullString   `json:"prompt_guidance"`
+	Rubric         *Rubric          `json:"rubric"`
+	MatchTargets   []string         `json:"match_targets,omitempty"`
 	CreatedAt      time.Time        `json:"created_at"`
 	UpdatedAt      time.Time        `json:"updated_at"`
 }
@@ -31,11 +46,28 @@ type ExerciseQuestionModel struct {
 	DB *sql.DB
 }
 
+func ValidateExerciseQuestion(v *validator.Validator, question *ExerciseQuestion) {
+	v.Check(question.Question != "", "question", "must be provided")
+	v.Check(len(question.Question) <= 5_000, "question", "can't exceed 5000 chars")
+
+	v.Check(validator.PermittedValues(question.ExerciseType, ExerciseTypes...), "type", "must be a supported question type")
+
+	v.Check(len(question.PromptGuidance.String) <= 5_000, "prompt_guidance", "can't exceed 5000 chars")
+
+	if question.Rubric != nil {
+		v.Check(question.ExerciseType == FreeTextType, "rubric", "is only allowed for free_text questions")
+		validateNested(v, "rubric", func(v *validator.Validator) {
+			ValidateRubric(v, question.Rubric)
+		})
+	}
+}
+
 func (em *ExerciseQuestionModel) GetByExerciseID(exerciseID uuid.UUID) ([]ExerciseQuestion, error) {
 	query := `
-	SELECT id, type, question, prompt_guidance, created_at, updated_at
+	SELECT id, type, question, "order", prompt_guidance, rubric, created_at, updated_at
 	FROM exercise_questions
-	WHERE exercise_id = $1`
+	WHERE exercise_id = $1
+	ORDER BY "order", created_at`
 	var questionSlice []ExerciseQuestion
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -47,7 +79,7 @@ func (em *ExerciseQuestionModel) GetByExerciseID(exerciseID uuid.UUID) ([]Exerci
 	for rows.Next() {
 		var q ExerciseQuestion
 		q.ExerciseID = exerciseID
-		if err := rows.Scan(&q.ID, &q.ExerciseType, &q.Question, &q.PromptGuidance, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		if err := rows.Scan(&q.ID, &q.ExerciseType, &q.Question, &q.Order, &q.PromptGuidance, &q.Rubric, &q.CreatedAt, &q.UpdatedAt); err != nil {
 			return nil, err
 		}
 		questionSlice = append(questionSlice, q)
@@ -57,7 +89,7 @@ func (em *ExerciseQuestionModel) GetByExerciseID(exerciseID uuid.UUID) ([]Exerci
 
 func (em *ExerciseQuestionModel) GetAllByScenarioIDAsMap(scenarioID uuid.UUID) (map[uuid.UUID]ExerciseQuestion, error) {
 	query := `
-	SELECT eq.id, eq.exercise_id, eq.type, eq.question, eq.prompt_guidance, eq.created_at, eq.updated_at
+	SELECT eq.id, eq.exercise_id, eq.type, eq.question, eq."order", eq.prompt_guidance, eq.rubric, eq.created_at, eq.updated_at
 	FROM exercise_questions eq
 	INNER JOIN exercises e ON eq.exercise_id = e.id
 	WHERE e.scenario_id = $1`
@@ -71,10 +103,88 @@ func (em *ExerciseQuestionModel) GetAllByScenarioIDAsMap(scenarioID uuid.UUID) (
 	questionMap := make(map[uuid.UUID]ExerciseQuestion)
 	for rows.Next() {
 		var q ExerciseQuestion
-		if err := rows.Scan(&q.ID, &q.ExerciseID, &q.ExerciseType, &q.Question, &q.PromptGuidance, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		if err := rows.Scan(&q.ID, &q.ExerciseID, &q.ExerciseType, &q.Question, &q.Order, &q.PromptGuidance, &q.Rubric, &q.CreatedAt, &q.UpdatedAt); err != nil {
 			return nil, err
 		}
 		questionMap[q.ID] = q
 	}
 	return questionMap, nil
 }
+
+func (em *ExerciseQuestionModel) Get(id uuid.UUID) (*ExerciseQuestion, error) {
+	query := `
+	SELECT id, exercise_id, type, question, "order", prompt_guidance, rubric, created_at, updated_at
+	FROM exercise_questions
+	WHERE id = $1`
+	var q ExerciseQuestion
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := em.DB.QueryRowContext(ctx, query, id).Scan(
+		&q.ID, &q.ExerciseID, &q.ExerciseType, &q.Question, &q.Order, &q.PromptGuidance, &q.Rubric, &q.CreatedAt, &q.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &q, nil
+}
+
+func (em *ExerciseQuestionModel) Insert(question *ExerciseQuestion) error {
+	query := `
+	INSERT INTO exercise_questions (exercise_id, type, question, prompt_guidance, rubric, "order")
+	VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX("order"), 0) + 1 FROM exercise_questions WHERE exercise_id = $1))
+	RETURNING id, "order", created_at, updated_at`
+	args := []any{question.ExerciseID, question.ExerciseType, question.Question, question.PromptGuidance, question.Rubric}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return em.DB.QueryRowContext(ctx, query, args...).Scan(&question.ID, &question.Order, &question.CreatedAt, &question.UpdatedAt)
+}
+
+func (em *ExerciseQuestionModel) Update(question *ExerciseQuestion) error {
+	query := `
+	UPDATE exercise_questions
+	SET type = $1, question = $2, prompt_guidance = $3, rubric = $4, updated_at = now()
+	WHERE id = $5
+	RETURNING updated_at`
+	args := []any{question.ExerciseType, question.Question, question.PromptGuidance, question.Rubric, question.ID}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := em.DB.QueryRowContext(ctx, query, args...).Scan(&question.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (em *ExerciseQuestionModel) Delete(id uuid.UUID) error {
+	query := `
+	DELETE FROM exercise_questions
+	WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := em.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (em *ExerciseQuestionModel) Reorder(exerciseID uuid.UUID, ids []uuid.UUID) error {
+	return reorder(em.DB, "exercise_questions", "exercise_id", exerciseID, ids)
+}
@@ -3,25 +3,33 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/berberapan/info-eval/internal/validator"
 	"github.com/google/uuid"
 )
 
 type Exercise struct {
-	ID        uuid.UUID          `json:"id"`
-	Info      string             `json:"info"`
-	Order     int16              `json:"order"`
-	Media     []ExerciseMedia    `json:"media"`
-	Questions []ExerciseQuestion `json:"questions"`
-	CreatedAt time.Time          `json:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at"`
+	ID         uuid.UUID          `json:"id"`
+	ScenarioID uuid.UUID          `json:"scenario_id"`
+	Info       string             `json:"info"`
+	Order      int16              `json:"order"`
+	Media      []ExerciseMedia    `json:"media"`
+	Questions  []ExerciseQuestion `json:"questions"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
 }
 
 type ExerciseModel struct {
 	DB *sql.DB
 }
 
+func ValidateExercise(v *validator.Validator, exercise *Exercise) {
+	v.Check(exercise.Info != "", "info", "must be provided")
+	v.Check(len(exercise.Info) <= 20_000, "info", "can't exceed 20000 chars")
+}
+
 func (em *ExerciseModel) GetByScenarioID(scenarioID uuid.UUID) ([]Exercise, error) {
 	query := `
 	SELECT id, info, "order", created_at, updated_at
@@ -39,6 +47,7 @@ func (em *ExerciseModel) GetByScenarioID(scenarioID uuid.UUID) ([]Exercise, erro
 	defer rows.Close()
 	for rows.Next() {
 		var e Exercise
+		e.ScenarioID = scenarioID
 		if err := rows.Scan(&e.ID, &e.Info, &e.Order, &e.CreatedAt, &e.UpdatedAt); err != nil {
 			return nil, err
 		}
@@ -46,3 +55,80 @@ func (em *ExerciseModel) GetByScenarioID(scenarioID uuid.UUID) ([]Exercise, erro
 	}
 	return exercisSlice, nil
 }
+
+func (em *ExerciseModel) Get(id uuid.UUID) (*Exercise, error) {
+	query := `
+	SELECT id, scenario_id, info, "order", created_at, updated_at
+	FROM exercises
+	WHERE id = $1`
+	var e Exercise
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := em.DB.QueryRowContext(ctx, query, id).Scan(
+		&e.ID, &e.ScenarioID, &e.Info, &e.Order, &e.CreatedAt, &e.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &e, nil
+}
+
+func (em *ExerciseModel) Insert(exercise *Exercise) error {
+	query := `
+	INSERT INTO exercises (scenario_id, info, "order")
+	VALUES ($1, $2, (SELECT COALESCE(MAX("order"), 0) + 1 FROM exercises WHERE scenario_id = $1))
+	RETURNING id, "order", created_at, updated_at`
+	args := []any{exercise.ScenarioID, exercise.Info}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return em.DB.QueryRowContext(ctx, query, args...).Scan(&exercise.ID, &exercise.Order, &exercise.CreatedAt, &exercise.UpdatedAt)
+}
+
+func (em *ExerciseModel) Update(exercise *Exercise) error {
+	query := `
+	UPDATE exercises
+	SET info = $1, updated_at = now()
+	WHERE id = $2
+	RETURNING updated_at`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := em.DB.QueryRowContext(ctx, query, exercise.Info, exercise.ID).Scan(&exercise.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (em *ExerciseModel) Delete(id uuid.UUID) error {
+	query := `
+	DELETE FROM exercises
+	WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := em.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (em *ExerciseModel) Reorder(scenarioID uuid.UUID, ids []uuid.UUID) error {
+	return reorder(em.DB, "exercises", "scenario_id", scenarioID, ids)
+}
@@ -0,0 +1,159 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+type CachedFeedback struct {
+	Feedback         string
+	Assessment       *Assessment
+	PromptTemplateID uuid.NullUUID
+}
+
+type FeedbackCacheDay struct {
+	Day    time.Time `json:"day"`
+	Hits   int       `json:"hits"`
+	Misses int       `json:"misses"`
+}
+
+type FeedbackCacheStats struct {
+	Entries int                `json:"entries"`
+	Hits    int                `json:"hits"`
+	Misses  int                `json:"misses"`
+	HitRate float64            `json:"hit_rate"`
+	Days    []FeedbackCacheDay `json:"days"`
+}
+
+// NormalizeAnswer folds the differences that don't change what a student
+// meant, so "Nej", "nej." and " NEJ " share a cache entry.
+func NormalizeAnswer(answer string) string {
+	answer = strings.ToLower(strings.Join(strings.Fields(answer), " "))
+	return strings.TrimRightFunc(answer, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+func FeedbackCacheKey(model string, template *PromptTemplate, language Language, question *ExerciseQuestion, answer string) string {
+	rubric, _ := json.Marshal(question.Rubric)
+	parts := []string{
+		model,
+		string(template.Kind),
+		template.ID.String(),
+		strconv.Itoa(int(template.Version)),
+		string(language),
+		question.Question,
+		question.PromptGuidance.String,
+		string(rubric),
+		NormalizeAnswer(answer),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+type FeedbackCacheModel struct {
+	DB *sql.DB
+}
+
+func (fm *FeedbackCacheModel) Get(key string) (*CachedFeedback, error) {
+	query := `
+	UPDATE feedback_cache
+	SET hits = hits + 1
+	WHERE key = $1 AND expires_at > now()
+	RETURNING feedback, assessment, prompt_template_id`
+	var cached CachedFeedback
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := fm.DB.QueryRowContext(ctx, query, key).Scan(&cached.Feedback, &cached.Assessment, &cached.PromptTemplateID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &cached, nil
+}
+
+func (fm *FeedbackCacheModel) Set(key, model string, cached *CachedFeedback, ttl time.Duration) error {
+	query := `
+	INSERT INTO feedback_cache (key, feedback, assessment, prompt_template_id, model, expires_at)
+	VALUES ($1, $2, $3, $4, $5, now() + $6 * interval '1 second')
+	ON CONFLICT (key) DO UPDATE
+	SET feedback = EXCLUDED.feedback, assessment = EXCLUDED.assessment, prompt_template_id = EXCLUDED.prompt_template_id,
+		hits = 0, created_at = now(), expires_at = EXCLUDED.expires_at`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := fm.DB.ExecContext(ctx, query, key, cached.Feedback, cached.Assessment, cached.PromptTemplateID, model, ttl.Seconds())
+	return err
+}
+
+func (fm *FeedbackCacheModel) RecordLookup(hit bool) error {
+	query := `
+	INSERT INTO feedback_cache_stats (day, hits, misses)
+	VALUES (CURRENT_DATE, CASE WHEN $1 THEN 1 ELSE 0 END, CASE WHEN $1 THEN 0 ELSE 1 END)
+	ON CONFLICT (day) DO UPDATE
+	SET hits = feedback_cache_stats.hits + EXCLUDED.hits, misses = feedback_cache_stats.misses + EXCLUDED.misses`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := fm.DB.ExecContext(ctx, query, hit)
+	return err
+}
+
+func (fm *FeedbackCacheModel) DeleteExpired() (int64, error) {
+	query := `DELETE FROM feedback_cache WHERE expires_at <= now()`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := fm.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (fm *FeedbackCacheModel) Stats(days int) (*FeedbackCacheStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	stats := FeedbackCacheStats{Days: []FeedbackCacheDay{}}
+	err := fm.DB.QueryRowContext(ctx, `SELECT count(*) FROM feedback_cache WHERE expires_at > now()`).Scan(&stats.Entries)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+	SELECT day, hits, misses
+	FROM feedback_cache_stats
+	WHERE day > CURRENT_DATE - $1::int
+	ORDER BY day DESC`
+	rows, err := fm.DB.QueryContext(ctx, query, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day FeedbackCacheDay
+		if err := rows.Scan(&day.Day, &day.Hits, &day.Misses); err != nil {
+			return nil, err
+		}
+		stats.Days = append(stats.Days, day)
+		stats.Hits += day.Hits
+		stats.Misses += day.Misses
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return &stats, nil
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/google/uuid"
+)
+
+func TestNormalizeAnswer(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   string
+	}{
+		{name: "Case and whitespace", answer: "  NEJ \n", want: "nej"},
+		{name: "Trailing punctuation", answer: "Vet inte...", want: "vet inte"},
+		{name: "Inner whitespace", answer: "Vet \t  inte", want: "vet inte"},
+		{name: "Inner punctuation kept", answer: "Nej, det är reklam!", want: "nej, det är reklam"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, NormalizeAnswer(tt.answer), tt.want)
+		})
+	}
+}
+
+func TestFeedbackCacheKey(t *testing.T) {
+	template := DefaultPromptTemplates[PromptFreeText]
+	question := &ExerciseQuestion{
+		ID:             uuid.New(),
+		ExerciseType:   FreeTextType,
+		Question:       "Är källan trovärdig?",
+		PromptGuidance: sql.NullString{String: "Nej, det är reklam", Valid: true},
+	}
+	key := FeedbackCacheKey("gemini/flash", template, LanguageSwedish, question, "Nej")
+
+	assert.Equal(t, FeedbackCacheKey("gemini/flash", template, LanguageSwedish, question, " nej. "), key)
+	other := *question
+	other.ID = uuid.New()
+	assert.Equal(t, FeedbackCacheKey("gemini/flash", template, LanguageSwedish, &other, "Nej"), key)
+
+	newer := *template
+	newer.ID = uuid.New()
+	newer.Version = 2
+	guidance := *question
+	guidance.PromptGuidance = sql.NullString{String: "Ja", Valid: true}
+	rubric := *question
+	rubric.Rubric = &Rubric{Criteria: []RubricCriterion{{Name: "Avsändare"}}}
+
+	for name, changed := range map[string]string{
+		"Model":    FeedbackCacheKey("openai/gpt", template, LanguageSwedish, question, "Nej"),
+		"Template": FeedbackCacheKey("gemini/flash", &newer, LanguageSwedish, question, "Nej"),
+		"Language": FeedbackCacheKey("gemini/flash", template, LanguageEnglish, question, "Nej"),
+		"Guidance": FeedbackCacheKey("gemini/flash", template, LanguageSwedish, &guidance, "Nej"),
+		"Rubric":   FeedbackCacheKey("gemini/flash", template, LanguageSwedish, &rubric, "Nej"),
+		"Answer":   FeedbackCacheKey("gemini/flash", template, LanguageSwedish, question, "Ja"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, changed != key, true)
+		})
+	}
+}
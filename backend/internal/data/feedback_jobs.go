@@ -0,0 +1,168 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FeedbackJobStatus string
+
+const (
+	FeedbackJobPending FeedbackJobStatus = "pending"
+	FeedbackJobRunning FeedbackJobStatus = "running"
+	FeedbackJobDone    FeedbackJobStatus = "done"
+	FeedbackJobDead    FeedbackJobStatus = "dead"
+)
+
+type FeedbackJob struct {
+	ID                uuid.UUID         `json:"id"`
+	SessionResponseID uuid.UUID         `json:"session_response_id"`
+	Status            FeedbackJobStatus `json:"status"`
+	Attempts          int               `json:"attempts"`
+	MaxAttempts       int               `json:"max_attempts"`
+	RunAt             time.Time         `json:"run_at"`
+	LastError         sql.NullString    `json:"last_error"`
+}
+
+// ErrJobLost is returned when a job's lease ran out and it was requeued or
+// claimed again while the worker was still on it.
+var ErrJobLost = errors.New("feedback job is no longer held by this worker")
+
+type FeedbackJobModel struct {
+	DB *sql.DB
+}
+
+func (fm *FeedbackJobModel) Claim() (*FeedbackJob, error) {
+	query := `
+	UPDATE feedback_jobs
+	SET status = 'running', attempts = attempts + 1, locked_at = now(), updated_at = now()
+	WHERE id = (
+		SELECT id FROM feedback_jobs
+		WHERE status = 'pending' AND run_at <= now()
+		ORDER BY run_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, session_response_id, status, attempts, max_attempts, run_at, last_error`
+	var job FeedbackJob
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := fm.DB.QueryRowContext(ctx, query).Scan(
+		&job.ID, &job.SessionResponseID, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt, &job.LastError,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &job, nil
+}
+
+// Complete, Fail, Heartbeat and Release only touch the job while it's still
+// running under the claim that job came from; the attempt count tells one
+// claim from the next.
+func (fm *FeedbackJobModel) Complete(job *FeedbackJob) error {
+	query := `
+	UPDATE feedback_jobs
+	SET status = 'done', locked_at = NULL, last_error = NULL, updated_at = now()
+	WHERE id = $1 AND status = 'running' AND attempts = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := fm.DB.ExecContext(ctx, query, job.ID, job.Attempts)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrJobLost
+	}
+	job.Status = FeedbackJobDone
+	return nil
+}
+
+func (fm *FeedbackJobModel) Fail(job *FeedbackJob, jobErr error, retryIn time.Duration) error {
+	job.Status = FeedbackJobPending
+	if job.Attempts >= job.MaxAttempts {
+		job.Status = FeedbackJobDead
+	}
+	job.LastError = sql.NullString{String: jobErr.Error(), Valid: true}
+	query := `
+	UPDATE feedback_jobs
+	SET status = $1, run_at = now() + make_interval(secs => $2), locked_at = NULL, last_error = $3, updated_at = now()
+	WHERE id = $4 AND status = 'running' AND attempts = $5
+	RETURNING run_at`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := fm.DB.QueryRowContext(ctx, query, job.Status, retryIn.Seconds(), job.LastError, job.ID, job.Attempts).Scan(&job.RunAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrJobLost
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Heartbeat renews the lease on a running job so RequeueStale leaves it alone
+// however long it takes.
+func (fm *FeedbackJobModel) Heartbeat(job *FeedbackJob) error {
+	query := `
+	UPDATE feedback_jobs
+	SET locked_at = now(), updated_at = now()
+	WHERE id = $1 AND status = 'running' AND attempts = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := fm.DB.ExecContext(ctx, query, job.ID, job.Attempts)
+	return err
+}
+
+// Release puts a job that was interrupted by shutdown back in the queue
+// without counting the attempt.
+func (fm *FeedbackJobModel) Release(job *FeedbackJob) error {
+	query := `
+	UPDATE feedback_jobs
+	SET status = 'pending', attempts = GREATEST(attempts - 1, 0), locked_at = NULL, updated_at = now()
+	WHERE id = $1 AND status = 'running' AND attempts = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := fm.DB.ExecContext(ctx, query, job.ID, job.Attempts)
+	return err
+}
+
+// RequeueStale puts running jobs whose lease ran out back in the queue. A
+// job that has used up its attempts is marked dead instead, and the feedback
+// still pending for its response is marked failed, as Fail would have done.
+func (fm *FeedbackJobModel) RequeueStale(lease time.Duration) (int64, error) {
+	query := `
+	WITH stale AS (
+		UPDATE feedback_jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead'::feedback_job_status ELSE 'pending'::feedback_job_status END,
+			last_error = CASE WHEN attempts >= max_attempts THEN COALESCE(last_error, $2) ELSE last_error END,
+			locked_at = NULL, updated_at = now()
+		WHERE status = 'running' AND locked_at < now() - make_interval(secs => $1)
+		RETURNING session_response_id, status, last_error
+	), failed AS (
+		UPDATE response_feedback rf
+		SET status = 'failed', error = COALESCE(rf.error, stale.last_error), updated_at = now()
+		FROM stale
+		WHERE rf.session_response_id = stale.session_response_id AND stale.status = 'dead' AND rf.status = 'pending'
+	)
+	SELECT count(*) FROM stale`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var count int64
+	err := fm.DB.QueryRowContext(ctx, query, lease.Seconds(), "lease expired after the last attempt").Scan(&count)
+	return count, err
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+type Language string
+
+const (
+	LanguageSwedish Language = "sv"
+	LanguageEnglish Language = "en"
+)
+
+const DefaultLanguage = LanguageSwedish
+
+var Languages = []Language{LanguageSwedish, LanguageEnglish}
+
+var languageNames = map[Language]string{
+	LanguageSwedish: "Swedish",
+	LanguageEnglish: "English",
+}
+
+func (l Language) Name() string {
+	if name, ok := languageNames[l]; ok {
+		return name
+	}
+	return languageNames[DefaultLanguage]
+}
+
+func (l Language) OrDefault() Language {
+	if l == "" {
+		return DefaultLanguage
+	}
+	return l
+}
+
+func ValidateLanguage(v *validator.Validator, key string, language Language) {
+	supported := make([]string, len(Languages))
+	for i, l := range Languages {
+		supported[i] = string(l)
+	}
+	v.Check(validator.PermittedValues(language, Languages...), key, "must be one of "+strings.Join(supported, ", "))
+}
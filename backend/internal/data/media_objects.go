@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MediaObject struct {
+	ID          uuid.UUID     `json:"id"`
+	SHA256      string        `json:"sha256"`
+	ContentType string        `json:"content_type"`
+	MediaType   MediaType     `json:"media_type"`
+	SizeBytes   int64         `json:"size_bytes"`
+	UploadedBy  uuid.NullUUID `json:"uploaded_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type MediaObjectModel struct {
+	DB *sql.DB
+}
+
+func MediaTypeForContentType(contentType string) (MediaType, bool) {
+	mimeType, _, _ := strings.Cut(contentType, ";")
+	switch {
+	case strings.HasPrefix(mimeType, "image/") && mimeType != "image/svg+xml":
+		return ImageType, true
+	case strings.HasPrefix(mimeType, "video/"):
+		return VideoType, true
+	case strings.HasPrefix(mimeType, "audio/"), mimeType == "application/ogg":
+		return AudioType, true
+	default:
+		return "", false
+	}
+}
+
+func (mm *MediaObjectModel) Get(id uuid.UUID) (*MediaObject, error) {
+	query := `
+	SELECT id, sha256, content_type, media_type, size_bytes, uploaded_by, created_at
+	FROM media_objects
+	WHERE id = $1`
+	var m MediaObject
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := mm.DB.QueryRowContext(ctx, query, id).Scan(
+		&m.ID, &m.SHA256, &m.ContentType, &m.MediaType, &m.SizeBytes, &m.UploadedBy, &m.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+func (mm *MediaObjectModel) GetBySHA256(sum string) (*MediaObject, error) {
+	query := `
+	SELECT id, sha256, content_type, media_type, size_bytes, uploaded_by, created_at
+	FROM media_objects
+	WHERE sha256 = $1`
+	var m MediaObject
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := mm.DB.QueryRowContext(ctx, query, sum).Scan(
+		&m.ID, &m.SHA256, &m.ContentType, &m.MediaType, &m.SizeBytes, &m.UploadedBy, &m.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// Insert stores m unless an object with the same hash is already there, as
+// when two identical files are uploaded at once. Then m is filled in from the
+// existing row and Insert returns false.
+func (mm *MediaObjectModel) Insert(m *MediaObject) (bool, error) {
+	query := `
+	INSERT INTO media_objects (sha256, content_type, media_type, size_bytes, uploaded_by)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (sha256) DO NOTHING
+	RETURNING id, created_at`
+	args := []any{m.SHA256, m.ContentType, m.MediaType, m.SizeBytes, m.UploadedBy}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := mm.DB.QueryRowContext(ctx, query, args...).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+		existing, err := mm.GetBySHA256(m.SHA256)
+		if err != nil {
+			return false, err
+		}
+		*m = *existing
+		return false, nil
+	}
+	return true, nil
+}
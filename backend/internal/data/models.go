@@ -1,8 +1,11 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -10,6 +13,8 @@ import (
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrEditConflict   = errors.New("edit conflict")
+	ErrOrderMismatch  = errors.New("order must list every item exactly once")
+	ErrScenarioInUse  = errors.New("scenario has sessions")
 )
 
 type ExerciseStore interface {
@@ -29,23 +34,45 @@ type QuestionOptionStore interface {
 }
 
 type Models struct {
+	Bundles           BundleModel
+	ClassReports      ClassReportModel
 	Exercises         ExerciseModel
 	Scenarios         ScenarioModel
 	ExerciseMedia     ExerciseMediaModel
 	ExerciseQuestions ExerciseQuestionModel
+	FeedbackCache     FeedbackCacheModel
+	FeedbackJobs      FeedbackJobModel
+	MediaObjects      MediaObjectModel
+	PromptTemplates   PromptTemplateModel
+	QuestionDrafts    QuestionDraftModel
 	QuestionOptions   QuestionOptionModel
+	Redactions        RedactionModel
+	ResponseFeedback  ResponseFeedbackModel
+	ScenarioRevisions ScenarioRevisionModel
 	ScenarioSessions  ScenarioSessionModel
+	ScenarioShares    ScenarioShareModel
 	SessionResponses  SessionResponseModel
 	Users             UserModel
 }
 
 func NewModels(db *sql.DB) Models {
 	models := Models{
+		Bundles:           BundleModel{DB: db},
+		ClassReports:      ClassReportModel{DB: db},
 		Exercises:         ExerciseModel{DB: db},
 		ExerciseMedia:     ExerciseMediaModel{DB: db},
 		ExerciseQuestions: ExerciseQuestionModel{DB: db},
+		FeedbackCache:     FeedbackCacheModel{DB: db},
+		FeedbackJobs:      FeedbackJobModel{DB: db},
+		MediaObjects:      MediaObjectModel{DB: db},
+		PromptTemplates:   PromptTemplateModel{DB: db},
+		QuestionDrafts:    QuestionDraftModel{DB: db},
 		QuestionOptions:   QuestionOptionModel{DB: db},
+		Redactions:        RedactionModel{DB: db},
+		ResponseFeedback:  ResponseFeedbackModel{DB: db},
+		ScenarioRevisions: ScenarioRevisionModel{DB: db},
 		ScenarioSessions:  ScenarioSessionModel{DB: db},
+		ScenarioShares:    ScenarioShareModel{DB: db},
 		SessionResponses:  SessionResponseModel{DB: db},
 		Users:             UserModel{DB: db},
 	}
@@ -58,3 +85,48 @@ func NewModels(db *sql.DB) Models {
 	}
 	return models
 }
+
+func reorder(db *sql.DB, table, parentColumn string, parentID uuid.UUID, ids []uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT id FROM %s WHERE %s = $1 FOR UPDATE`, table, parentColumn), parentID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) != len(existing) {
+		return ErrOrderMismatch
+	}
+	for _, id := range ids {
+		if !existing[id] {
+			return ErrOrderMismatch
+		}
+		delete(existing, id)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET "order" = $1, updated_at = now() WHERE id = $2`, table)
+	for i, id := range ids {
+		if _, err := tx.ExecContext(ctx, query, i+1, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
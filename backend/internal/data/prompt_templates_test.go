@@ -0,0 +1,87 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func TestDefaultPromptTemplates(t *testing.T) {
+	for _, kind := range PromptKinds {
+		t.Run(string(kind), func(t *testing.T) {
+			v := validator.New()
+			ValidatePromptTemplate(v, DefaultPromptTemplates[kind])
+			assert.Equal(t, v.Valid(), true)
+		})
+	}
+
+	prompt, err := DefaultPromptTemplates[PromptRubric].Render(samplePromptVars)
+	assert.NilError(t, err)
+	assert.StringContains(t, prompt, "Consider the following guidance when evaluating the answer: \"Titta på Om oss\"")
+	assert.StringContains(t, prompt, "Criterion \"Avsändare\":\n- \"Saknas\": Nämner inte avsändaren\n")
+	assert.StringContains(t, prompt, "should be in Swedish")
+
+	vars := samplePromptVars
+	vars.Guidance = ""
+	prompt, err = DefaultPromptTemplates[PromptFreeText].Render(vars)
+	assert.NilError(t, err)
+	assert.StringContains(t, prompt, "\"Vem står bakom sidan?\"\nThe student's answer is enclosed in <student_answer> tags below.")
+	assert.StringContains(t, prompt, "\n<student_answer>\nEn myndighet\n</student_answer>\n\n")
+
+	prompt, err = DefaultPromptTemplates[PromptSummary].Render(samplePromptVars)
+	assert.NilError(t, err)
+	assert.StringContains(t, prompt, "Question: \"Är bilden äkta?\"\n<student_answer>\nJa\n</student_answer>\nFeedback already given on this answer: \"Incorrect\"\n")
+	assert.StringContains(t, prompt, "in Swedish")
+
+	prompt, err = DefaultPromptTemplates[PromptClassReport].Render(samplePromptVars)
+	assert.NilError(t, err)
+	assert.StringContains(t, prompt, "\nAnswer 2:\n<student_answer>\nJa\n</student_answer>\n")
+	assert.StringContains(t, prompt, "Consider the following guidance when reading the answers: \"Titta på Om oss\"")
+
+	prompt, err = DefaultPromptTemplates[PromptQuestionDrafts].Render(samplePromptVars)
+	assert.NilError(t, err)
+	assert.StringContains(t, prompt, "<exercise_info>\nLäs artikeln från Krisinformation.se om översvämningarna.\n</exercise_info>\n")
+	assert.StringContains(t, prompt, "- image \"oversvamning.jpg\"\n- video (video/mp4)\n")
+	assert.StringContains(t, prompt, "for each of these types, in this order: free_text, multiple_choice.\n")
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template PromptTemplate
+		wantKeys []string
+	}{
+		{
+			name:     "Valid",
+			template: PromptTemplate{Kind: PromptFreeText, Body: "Svara på {{.Language}}: {{.Answer}}"},
+		},
+		{
+			name:     "Unknown kind and empty body",
+			template: PromptTemplate{Kind: "essay", Body: " "},
+			wantKeys: []string{"kind", "body"},
+		},
+		{
+			name:     "Syntax error",
+			template: PromptTemplate{Kind: PromptFreeText, Body: "{{if .Answer}}"},
+			wantKeys: []string{"body"},
+		},
+		{
+			name:     "Unknown variable",
+			template: PromptTemplate{Kind: PromptFreeText, Body: "{{.Student}}"},
+			wantKeys: []string{"body"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidatePromptTemplate(v, &tt.template)
+			assert.Equal(t, len(v.Errors), len(tt.wantKeys))
+			for _, key := range tt.wantKeys {
+				_, ok := v.Errors[key]
+				assert.Equal(t, ok, true)
+			}
+		})
+	}
+}
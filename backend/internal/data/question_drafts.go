@@ -0,0 +1,205 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+// A QuestionDraft is a generated question waiting for a teacher to accept it
+// into the exercise or discard it. The draft uses the bundle format, so it
+// carries its options along until it's accepted.
+type QuestionDraft struct {
+	ID               uuid.UUID      `json:"id"`
+	ExerciseID       uuid.UUID      `json:"exercise_id"`
+	Question         BundleQuestion `json:"question"`
+	PromptTemplateID uuid.NullUUID  `json:"-"`
+	CreatedBy        uuid.NullUUID  `json:"created_by"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+var draftOptionCounts = map[ExerciseType][2]int{
+	TrueFalseType:      {2, 2},
+	MultipleChoiceType: {3, 5},
+	MultiSelectType:    {3, 6},
+	OrderingType:       {3, 6},
+	MatchingType:       {2, 6},
+	LikertType:         {3, 7},
+}
+
+// ValidateQuestionDraft holds a draft to more than a stored question needs:
+// it has to be usable as soon as it's accepted.
+func ValidateQuestionDraft(v *validator.Validator, q *BundleQuestion) {
+	ValidateBundleQuestion(v, q)
+	v.Check(q.PromptGuidance != "", "prompt_guidance", "must be provided")
+	if counts, ok := draftOptionCounts[q.Type]; ok {
+		v.Check(len(q.Options) >= counts[0] && len(q.Options) <= counts[1], "options", fmt.Sprintf("must contain between %d and %d options", counts[0], counts[1]))
+	}
+	correct := 0
+	matchTexts := make([]string, 0, len(q.Options))
+	for i, o := range q.Options {
+		v.Check(o.Feedback != "", fmt.Sprintf("options[%d].feedback", i), "must be provided")
+		if o.IsCorrect {
+			correct++
+		}
+		matchTexts = append(matchTexts, o.MatchText)
+	}
+	switch q.Type {
+	case TrueFalseType, MultipleChoiceType:
+		v.Check(correct == 1, "options", "must have exactly one correct option")
+	case MultiSelectType:
+		v.Check(correct >= 1, "options", "must have at least one correct option")
+	case LikertType:
+		v.Check(correct <= 1, "options", "can't have more than one correct option")
+	case MatchingType:
+		v.Check(validator.Unique(matchTexts), "options", "must not contain duplicate match texts")
+	}
+}
+
+type QuestionDraftModel struct {
+	DB *sql.DB
+}
+
+func (qm *QuestionDraftModel) Insert(drafts []*QuestionDraft) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := qm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `
+	INSERT INTO question_drafts (exercise_id, draft, prompt_template_id, created_by)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`
+	for _, d := range drafts {
+		js, err := json.Marshal(d.Question)
+		if err != nil {
+			return err
+		}
+		if err := tx.QueryRowContext(ctx, query, d.ExerciseID, js, d.PromptTemplateID, d.CreatedBy).Scan(&d.ID, &d.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (qm *QuestionDraftModel) Get(id uuid.UUID) (*QuestionDraft, error) {
+	query := `
+	SELECT id, exercise_id, draft, prompt_template_id, created_by, created_at
+	FROM question_drafts
+	WHERE id = $1`
+	var d QuestionDraft
+	var js []byte
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := qm.DB.QueryRowContext(ctx, query, id).Scan(&d.ID, &d.ExerciseID, &js, &d.PromptTemplateID, &d.CreatedBy, &d.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(js, &d.Question); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (qm *QuestionDraftModel) GetByExerciseID(exerciseID uuid.UUID) ([]*QuestionDraft, error) {
+	query := `
+	SELECT id, exercise_id, draft, prompt_template_id, created_by, created_at
+	FROM question_drafts
+	WHERE exercise_id = $1
+	ORDER BY created_at, id`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := qm.DB.QueryContext(ctx, query, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	drafts := []*QuestionDraft{}
+	for rows.Next() {
+		var d QuestionDraft
+		var js []byte
+		if err := rows.Scan(&d.ID, &d.ExerciseID, &js, &d.PromptTemplateID, &d.CreatedBy, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(js, &d.Question); err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+// Accept turns the draft into a question at the end of its exercise and
+// removes the draft. Accepting the same draft twice finds no record the
+// second time.
+func (qm *QuestionDraftModel) Accept(id uuid.UUID) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := qm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+	var exerciseID uuid.UUID
+	var js []byte
+	err = tx.QueryRowContext(ctx, `
+	DELETE FROM question_drafts
+	WHERE id = $1
+	RETURNING exercise_id, draft`, id).Scan(&exerciseID, &js)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return uuid.Nil, ErrRecordNotFound
+		default:
+			return uuid.Nil, err
+		}
+	}
+	var q BundleQuestion
+	if err := json.Unmarshal(js, &q); err != nil {
+		return uuid.Nil, err
+	}
+	questionID, err := insertBundleQuestion(ctx, tx, exerciseID, &q)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return questionID, nil
+}
+
+func (qm *QuestionDraftModel) Delete(id uuid.UUID) error {
+	query := `
+	DELETE FROM question_drafts
+	WHERE id = $1`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := qm.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
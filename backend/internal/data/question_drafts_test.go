@@ -0,0 +1,70 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func TestValidateQuestionDraft(t *testing.T) {
+	option := func(text string, correct bool) BundleOption {
+		return BundleOption{OptionText: text, IsCorrect: correct, Feedback: "Därför"}
+	}
+	tests := []struct {
+		name     string
+		question BundleQuestion
+		wantKeys []string
+	}{
+		{
+			name:     "Free text",
+			question: BundleQuestion{Type: FreeTextType, Question: "Vem står bakom?", PromptGuidance: "Avsändaren är en myndighet"},
+		},
+		{
+			name:     "Free text without guidance",
+			question: BundleQuestion{Type: FreeTextType, Question: "Vem står bakom?"},
+			wantKeys: []string{"prompt_guidance"},
+		},
+		{
+			name: "True or false with two correct options",
+			question: BundleQuestion{Type: TrueFalseType, Question: "Bilden är äkta", PromptGuidance: "G",
+				Options: []BundleOption{option("Sant", true), option("Falskt", true)}},
+			wantKeys: []string{"options"},
+		},
+		{
+			name: "Multiple choice with too few options",
+			question: BundleQuestion{Type: MultipleChoiceType, Question: "Vem?", PromptGuidance: "G",
+				Options: []BundleOption{option("A", true), option("B", false)}},
+			wantKeys: []string{"options"},
+		},
+		{
+			name: "Multi select without feedback",
+			question: BundleQuestion{Type: MultiSelectType, Question: "Vilka?", PromptGuidance: "G",
+				Options: []BundleOption{option("A", true), option("B", false), {OptionText: "C"}}},
+			wantKeys: []string{"options[2].feedback"},
+		},
+		{
+			name: "Matching with duplicate targets",
+			question: BundleQuestion{Type: MatchingType, Question: "Para ihop", PromptGuidance: "G",
+				Options: []BundleOption{{OptionText: "A", MatchText: "SVT", Feedback: "F"}, {OptionText: "B", MatchText: "SVT", Feedback: "F"}}},
+			wantKeys: []string{"options"},
+		},
+		{
+			name: "Ordering",
+			question: BundleQuestion{Type: OrderingType, Question: "Ordna", PromptGuidance: "G",
+				Options: []BundleOption{option("Först", false), option("Sedan", false), option("Sist", false)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateQuestionDraft(v, &tt.question)
+			assert.Equal(t, len(v.Errors), len(tt.wantKeys))
+			for _, key := range tt.wantKeys {
+				_, ok := v.Errors[key]
+				assert.Equal(t, ok, true)
+			}
+		})
+	}
+}
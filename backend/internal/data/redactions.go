@@ -0,0 +1,80 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A Redaction records that one piece of personal data was kept from a model
+// call. The value itself is never stored, only what kind it was and where it
+// came from.
+type Redaction struct {
+	ID                int64         `json:"id"`
+	Kind              string        `json:"kind"`
+	Purpose           string        `json:"purpose"`
+	SessionResponseID uuid.NullUUID `json:"session_response_id"`
+	ScenarioSessionID uuid.NullUUID `json:"scenario_session_id"`
+	QuestionID        uuid.NullUUID `json:"question_id"`
+	Provider          string        `json:"provider"`
+	CreatedAt         time.Time     `json:"created_at"`
+}
+
+const MaxRedactionsListed = 1000
+
+type RedactionModel struct {
+	DB *sql.DB
+}
+
+func (rm *RedactionModel) Insert(redactions []*Redaction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := rm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `
+	INSERT INTO redactions (kind, purpose, session_response_id, scenario_session_id, question_id, provider)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+	for _, r := range redactions {
+		args := []any{r.Kind, r.Purpose, r.SessionResponseID, r.ScenarioSessionID, r.QuestionID, r.Provider}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&r.ID, &r.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetRecent returns the redactions of the last days, newest first, up to
+// MaxRedactionsListed.
+func (rm *RedactionModel) GetRecent(days int) ([]*Redaction, error) {
+	query := `
+	SELECT id, kind, purpose, session_response_id, scenario_session_id, question_id, provider, created_at
+	FROM redactions
+	WHERE created_at > now() - make_interval(days => $1)
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := rm.DB.QueryContext(ctx, query, days, MaxRedactionsListed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	redactions := []*Redaction{}
+	for rows.Next() {
+		var r Redaction
+		if err := rows.Scan(&r.ID, &r.Kind, &r.Purpose, &r.SessionResponseID, &r.ScenarioSessionID, &r.QuestionID, &r.Provider, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		redactions = append(redactions, &r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return redactions, nil
+}
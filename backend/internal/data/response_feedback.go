@@ -0,0 +1,233 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type FeedbackStatus string
+
+const (
+	FeedbackPending FeedbackStatus = "pending"
+	FeedbackDone    FeedbackStatus = "done"
+	FeedbackFailed  FeedbackStatus = "failed"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewUnreviewed ReviewStatus = "unreviewed"
+	ReviewApproved   ReviewStatus = "approved"
+	ReviewEdited     ReviewStatus = "edited"
+	ReviewReplaced   ReviewStatus = "replaced"
+)
+
+type ResponseFeedback struct {
+	SessionResponseID     uuid.UUID      `json:"-"`
+	QuestionID            uuid.UUID      `json:"question_id"`
+	Status                FeedbackStatus `json:"status"`
+	Feedback              string         `json:"feedback,omitempty"`
+	Error                 string         `json:"error,omitempty"`
+	Assessment            *Assessment    `json:"assessment,omitempty"`
+	PromptTemplateID      uuid.NullUUID  `json:"prompt_template_id,omitzero"`
+	PromptTemplateVersion int32          `json:"prompt_template_version,omitempty"`
+	ReviewStatus          ReviewStatus   `json:"review_status,omitempty"`
+	TeacherFeedback       string         `json:"teacher_feedback,omitempty"`
+	ReviewedBy            uuid.NullUUID  `json:"reviewed_by,omitzero"`
+	ReviewedAt            *time.Time     `json:"reviewed_at,omitempty"`
+	Flags                 []string       `json:"flags,omitempty"`
+	Held                  bool           `json:"held,omitempty"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+}
+
+func (rf *ResponseFeedback) Visible() string {
+	if rf.TeacherFeedback != "" {
+		return rf.TeacherFeedback
+	}
+	return rf.Feedback
+}
+
+// NeedsReview reports whether the answer was flagged as a possible prompt
+// injection and no teacher has looked at it yet.
+func (rf *ResponseFeedback) NeedsReview() bool {
+	return len(rf.Flags) > 0 && (rf.ReviewStatus == "" || rf.ReviewStatus == ReviewUnreviewed)
+}
+
+// ForStudent returns the version of the feedback students see: the teacher's
+// text when there is one, without the review details, and nothing while the
+// feedback is held or needs review. The model's rubric assessment goes with
+// the model's text, so it's dropped once a teacher has written their own.
+func (rf *ResponseFeedback) ForStudent(held bool) ResponseFeedback {
+	student := ResponseFeedback{
+		SessionResponseID: rf.SessionResponseID,
+		QuestionID:        rf.QuestionID,
+		Status:            rf.Status,
+		Feedback:          rf.Visible(),
+		Error:             rf.Error,
+		Assessment:        rf.Assessment,
+		UpdatedAt:         rf.UpdatedAt,
+	}
+	if rf.TeacherFeedback != "" {
+		student.Status = FeedbackDone
+		student.Error = ""
+		student.Assessment = nil
+	}
+	if held || rf.NeedsReview() {
+		student.Feedback = ""
+		student.Assessment = nil
+		student.Held = true
+	}
+	return student
+}
+
+func ValidateFeedbackReview(v *validator.Validator, rf *ResponseFeedback) {
+	v.Check(validator.PermittedValues(rf.ReviewStatus, ReviewApproved, ReviewEdited, ReviewReplaced), "review_status", "must be approved, edited or replaced")
+	v.Check(len(rf.TeacherFeedback) <= 5_000, "feedback", "can't exceed 5000 chars")
+
+	switch rf.ReviewStatus {
+	case ReviewApproved:
+		v.Check(rf.Status == FeedbackDone, "review_status", "only generated feedback can be approved")
+		v.Check(rf.TeacherFeedback == "", "feedback", "must be empty when approving")
+	case ReviewEdited:
+		v.Check(rf.Status == FeedbackDone, "review_status", "only generated feedback can be edited")
+		v.Check(rf.TeacherFeedback != "", "feedback", "must be provided")
+	case ReviewReplaced:
+		v.Check(rf.TeacherFeedback != "", "feedback", "must be provided")
+	}
+}
+
+type ResponseFeedbackModel struct {
+	DB *sql.DB
+}
+
+func (rm *ResponseFeedbackModel) query(where string, arg any) ([]ResponseFeedback, error) {
+	query := `
+	SELECT rf.session_response_id, rf.question_id, rf.status, COALESCE(rf.feedback, ''), COALESCE(rf.error, ''), rf.assessment, rf.prompt_template_id, COALESCE(pt.version, 0),
+		rf.review_status, COALESCE(rf.teacher_feedback, ''), rf.reviewed_by, rf.reviewed_at, rf.flags, rf.updated_at
+	FROM response_feedback rf
+	JOIN session_responses sr ON sr.id = rf.session_response_id
+	LEFT JOIN prompt_templates pt ON pt.id = rf.prompt_template_id
+	WHERE ` + where + `
+	ORDER BY rf.session_response_id, rf.question_id`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := rm.DB.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var states []ResponseFeedback
+	for rows.Next() {
+		var state ResponseFeedback
+		err := rows.Scan(&state.SessionResponseID, &state.QuestionID, &state.Status, &state.Feedback, &state.Error, &state.Assessment, &state.PromptTemplateID, &state.PromptTemplateVersion,
+			&state.ReviewStatus, &state.TeacherFeedback, &state.ReviewedBy, &state.ReviewedAt, pq.Array(&state.Flags), &state.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (rm *ResponseFeedbackModel) GetByResponseID(responseID uuid.UUID) ([]ResponseFeedback, error) {
+	return rm.query("rf.session_response_id = $1", responseID)
+}
+
+func (rm *ResponseFeedbackModel) GetBySessionID(scenarioSessionID uuid.UUID) (map[uuid.UUID][]ResponseFeedback, error) {
+	states, err := rm.query("sr.scenario_session_id = $1", scenarioSessionID)
+	if err != nil {
+		return nil, err
+	}
+	byResponse := make(map[uuid.UUID][]ResponseFeedback)
+	for _, state := range states {
+		byResponse[state.SessionResponseID] = append(byResponse[state.SessionResponseID], state)
+	}
+	return byResponse, nil
+}
+
+func (rm *ResponseFeedbackModel) MarkDone(responseID, questionID uuid.UUID, feedback string, assessment *Assessment, promptTemplateID uuid.NullUUID) error {
+	query := `
+	UPDATE response_feedback
+	SET status = 'done', feedback = $1, assessment = $2, prompt_template_id = $3, error = NULL, updated_at = now()
+	WHERE session_response_id = $4 AND question_id = $5`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := rm.DB.ExecContext(ctx, query, feedback, assessment, promptTemplateID, responseID, questionID)
+	return err
+}
+
+func (rm *ResponseFeedbackModel) SetError(responseID, questionID uuid.UUID, reason string) error {
+	query := `
+	UPDATE response_feedback
+	SET error = $1, updated_at = now()
+	WHERE session_response_id = $2 AND question_id = $3`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := rm.DB.ExecContext(ctx, query, reason, responseID, questionID)
+	return err
+}
+
+// MarkFailed gives up on one answer's feedback for good, for errors that
+// another attempt wouldn't fix.
+func (rm *ResponseFeedbackModel) MarkFailed(responseID, questionID uuid.UUID, reason string) error {
+	query := `
+	UPDATE response_feedback
+	SET status = 'failed', error = $1, updated_at = now()
+	WHERE session_response_id = $2 AND question_id = $3`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := rm.DB.ExecContext(ctx, query, reason, responseID, questionID)
+	return err
+}
+
+func (rm *ResponseFeedbackModel) FailPending(responseID uuid.UUID, reason string) error {
+	query := `
+	UPDATE response_feedback
+	SET status = 'failed', error = COALESCE(error, $1), updated_at = now()
+	WHERE session_response_id = $2 AND status = 'pending'`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := rm.DB.ExecContext(ctx, query, reason, responseID)
+	return err
+}
+
+func (rm *ResponseFeedbackModel) Review(rf *ResponseFeedback) error {
+	query := `
+	UPDATE response_feedback
+	SET review_status = $1, teacher_feedback = NULLIF($2, ''), reviewed_by = $3, reviewed_at = now(), updated_at = now()
+	WHERE session_response_id = $4 AND question_id = $5
+	RETURNING reviewed_at, updated_at`
+	args := []any{rf.ReviewStatus, rf.TeacherFeedback, rf.ReviewedBy, rf.SessionResponseID, rf.QuestionID}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := rm.DB.QueryRowContext(ctx, query, args...).Scan(&rf.ReviewedAt, &rf.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (rm *ResponseFeedbackModel) Flag(responseID, questionID uuid.UUID, flags []string) error {
+	query := `
+	UPDATE response_feedback
+	SET flags = $1, updated_at = now()
+	WHERE session_response_id = $2 AND question_id = $3`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := rm.DB.ExecContext(ctx, query, pq.Array(flags), responseID, questionID)
+	return err
+}
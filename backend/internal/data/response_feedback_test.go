@@ -0,0 +1,94 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+func TestResponseFeedbackForStudent(t *testing.T) {
+	reviewedAt := time.Now()
+	reviewed := ResponseFeedback{
+		QuestionID:      uuid.New(),
+		Status:          FeedbackPending,
+		Feedback:        "Modellens text",
+		Error:           "timeout",
+		Assessment:      &Assessment{Feedback: "Modellens bedömning"},
+		ReviewStatus:    ReviewReplaced,
+		TeacherFeedback: "Lärarens text",
+		ReviewedBy:      uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		ReviewedAt:      &reviewedAt,
+	}
+
+	student := reviewed.ForStudent(false)
+
+	assert.Equal(t, student.Status, FeedbackDone)
+	assert.Equal(t, student.Feedback, "Lärarens text")
+	assert.Equal(t, student.Error, "")
+	assert.Equal(t, student.Assessment == nil, true)
+	assert.Equal(t, student.TeacherFeedback, "")
+	assert.Equal(t, student.ReviewedBy.Valid, false)
+	assert.Equal(t, student.ReviewedAt == nil, true)
+
+	done := ResponseFeedback{Status: FeedbackDone, Feedback: "Modellens text", Assessment: &Assessment{Feedback: "Bra"}}
+	held := done.ForStudent(true)
+
+	assert.Equal(t, held.Held, true)
+	assert.Equal(t, held.Feedback, "")
+	assert.Equal(t, held.Assessment == nil, true)
+
+	flagged := ResponseFeedback{Status: FeedbackDone, Feedback: "Modellens text", ReviewStatus: ReviewUnreviewed, Flags: []string{"override_instructions"}}
+
+	assert.Equal(t, flagged.ForStudent(false).Held, true)
+
+	flagged.ReviewStatus = ReviewApproved
+
+	assert.Equal(t, flagged.ForStudent(false).Feedback, "Modellens text")
+}
+
+func TestValidateFeedbackReview(t *testing.T) {
+	tests := []struct {
+		name     string
+		review   ResponseFeedback
+		wantKeys []string
+	}{
+		{
+			name:   "Approve",
+			review: ResponseFeedback{Status: FeedbackDone, ReviewStatus: ReviewApproved},
+		},
+		{
+			name:     "Approve pending feedback",
+			review:   ResponseFeedback{Status: FeedbackPending, ReviewStatus: ReviewApproved, TeacherFeedback: "Text"},
+			wantKeys: []string{"review_status", "feedback"},
+		},
+		{
+			name:     "Edit without text",
+			review:   ResponseFeedback{Status: FeedbackDone, ReviewStatus: ReviewEdited},
+			wantKeys: []string{"feedback"},
+		},
+		{
+			name:   "Replace failed feedback",
+			review: ResponseFeedback{Status: FeedbackFailed, ReviewStatus: ReviewReplaced, TeacherFeedback: "Text"},
+		},
+		{
+			name:     "Unknown status",
+			review:   ResponseFeedback{Status: FeedbackDone, ReviewStatus: ReviewUnreviewed},
+			wantKeys: []string{"review_status"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateFeedbackReview(v, &tt.review)
+			assert.Equal(t, len(v.Errors), len(tt.wantKeys))
+			for _, key := range tt.wantKeys {
+				_, ok := v.Errors[key]
+				assert.Equal(t, ok, true)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+type Rubric struct {
+	Criteria []RubricCriterion `json:"criteria"`
+}
+
+type RubricCriterion struct {
+	Name   string        `json:"name"`
+	Levels []RubricLevel `json:"levels"`
+}
+
+type RubricLevel struct {
+	Label      string `json:"label"`
+	Points     int    `json:"points"`
+	Descriptor string `json:"descriptor"`
+}
+
+type Assessment struct {
+	Criteria []CriterionAssessment `json:"criteria"`
+	Feedback string                `json:"feedback"`
+	Score    int                   `json:"score"`
+	MaxScore int                   `json:"max_score"`
+}
+
+type CriterionAssessment struct {
+	Criterion     string `json:"criterion"`
+	Level         string `json:"level"`
+	Justification string `json:"justification"`
+}
+
+func (r *Rubric) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *Rubric) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("rubric must be scanned from JSON")
+	}
+	return json.Unmarshal(b, r)
+}
+
+func (a *Assessment) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *Assessment) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("assessment must be scanned from JSON")
+	}
+	return json.Unmarshal(b, a)
+}
+
+func (r *Rubric) criterion(name string) *RubricCriterion {
+	for i := range r.Criteria {
+		if r.Criteria[i].Name == name {
+			return &r.Criteria[i]
+		}
+	}
+	return nil
+}
+
+func (c *RubricCriterion) level(label string) *RubricLevel {
+	for i := range c.Levels {
+		if c.Levels[i].Label == label {
+			return &c.Levels[i]
+		}
+	}
+	return nil
+}
+
+func ValidateRubric(v *validator.Validator, r *Rubric) {
+	v.Check(len(r.Criteria) > 0, "criteria", "must contain at least one criterion")
+	v.Check(len(r.Criteria) <= 10, "criteria", "can't contain more than 10 criteria")
+
+	names := make([]string, len(r.Criteria))
+	for i, c := range r.Criteria {
+		names[i] = c.Name
+		key := fmt.Sprintf("criteria[%d]", i)
+		v.Check(c.Name != "", key+".name", "must be provided")
+		v.Check(len(c.Name) <= 200, key+".name", "can't exceed 200 chars")
+		v.Check(len(c.Levels) >= 2, key+".levels", "must contain at least two levels")
+		v.Check(len(c.Levels) <= 6, key+".levels", "can't contain more than 6 levels")
+
+		labels := make([]string, len(c.Levels))
+		for j, l := range c.Levels {
+			labels[j] = l.Label
+			levelKey := fmt.Sprintf("%s.levels[%d]", key, j)
+			v.Check(l.Label != "", levelKey+".label", "must be provided")
+			v.Check(len(l.Label) <= 100, levelKey+".label", "can't exceed 100 chars")
+			v.Check(l.Points >= 0, levelKey+".points", "can't be negative")
+			v.Check(l.Descriptor != "", levelKey+".descriptor", "must be provided")
+			v.Check(len(l.Descriptor) <= 1_000, levelKey+".descriptor", "can't exceed 1000 chars")
+		}
+		v.Check(validator.Unique(labels), key+".levels", "must have unique labels")
+	}
+	v.Check(validator.Unique(names), "criteria", "must have unique names")
+}
+
+func ValidateAssessment(v *validator.Validator, r *Rubric, a *Assessment) {
+	v.Check(a.Feedback != "", "feedback", "must be provided")
+	v.Check(len(a.Criteria) == len(r.Criteria), "criteria", fmt.Sprintf("must assess all %d criteria", len(r.Criteria)))
+
+	seen := make(map[string]bool)
+	for i, ca := range a.Criteria {
+		key := fmt.Sprintf("criteria[%d]", i)
+		c := r.criterion(ca.Criterion)
+		if c == nil {
+			v.AddError(key+".criterion", fmt.Sprintf("unknown criterion %q", ca.Criterion))
+			continue
+		}
+		v.Check(!seen[ca.Criterion], key+".criterion", fmt.Sprintf("criterion %q is assessed more than once", ca.Criterion))
+		seen[ca.Criterion] = true
+		v.Check(c.level(ca.Level) != nil, key+".level", fmt.Sprintf("unknown level %q for criterion %q", ca.Level, ca.Criterion))
+		v.Check(ca.Justification != "", key+".justification", "must be provided")
+	}
+}
+
+func (r *Rubric) Score(a *Assessment) {
+	a.Score, a.MaxScore = 0, 0
+	for _, c := range r.Criteria {
+		best := 0
+		for _, l := range c.Levels {
+			best = max(best, l.Points)
+		}
+		a.MaxScore += best
+	}
+	for _, ca := range a.Criteria {
+		if c := r.criterion(ca.Criterion); c != nil {
+			if l := c.level(ca.Level); l != nil {
+				a.Score += l.Points
+			}
+		}
+	}
+}
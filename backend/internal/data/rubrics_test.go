@@ -0,0 +1,118 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+func testRubric() *Rubric {
+	return &Rubric{
+		Criteria: []RubricCriterion{
+			{
+				Name: "Avsändare",
+				Levels: []RubricLevel{
+					{Label: "Saknas", Points: 0, Descriptor: "Nämner inte avsändaren"},
+					{Label: "Tydlig", Points: 2, Descriptor: "Identifierar avsändaren"},
+				},
+			},
+			{
+				Name: "Syfte",
+				Levels: []RubricLevel{
+					{Label: "Saknas", Points: 0, Descriptor: "Nämner inte syftet"},
+					{Label: "Delvis", Points: 1, Descriptor: "Antyder ett syfte"},
+					{Label: "Tydlig", Points: 3, Descriptor: "Förklarar syftet"},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateRubric(t *testing.T) {
+	v := validator.New()
+	ValidateRubric(v, testRubric())
+	assert.Equal(t, v.Valid(), true)
+
+	rubric := testRubric()
+	rubric.Criteria[1].Name = "Avsändare"
+	rubric.Criteria[0].Levels = rubric.Criteria[0].Levels[:1]
+	rubric.Criteria[1].Levels[2].Label = "Delvis"
+	v = validator.New()
+	ValidateRubric(v, rubric)
+	for _, key := range []string{"criteria", "criteria[0].levels", "criteria[1].levels"} {
+		_, ok := v.Errors[key]
+		assert.Equal(t, ok, true)
+	}
+
+	v = validator.New()
+	ValidateExerciseQuestion(v, &ExerciseQuestion{ExerciseType: MultipleChoiceType, Question: "Q", Rubric: testRubric()})
+	_, ok := v.Errors["rubric"]
+	assert.Equal(t, ok, true)
+}
+
+func TestValidateAssessment(t *testing.T) {
+	tests := []struct {
+		name       string
+		assessment Assessment
+		wantKeys   []string
+	}{
+		{
+			name: "Valid",
+			assessment: Assessment{
+				Feedback: "Bra",
+				Criteria: []CriterionAssessment{
+					{Criterion: "Avsändare", Level: "Tydlig", Justification: "Nämner SVT"},
+					{Criterion: "Syfte", Level: "Delvis", Justification: "Antyder"},
+				},
+			},
+		},
+		{
+			name: "Unknown level and missing criterion",
+			assessment: Assessment{
+				Criteria: []CriterionAssessment{
+					{Criterion: "Avsändare", Level: "Delvis", Justification: "Nämner SVT"},
+				},
+			},
+			wantKeys: []string{"feedback", "criteria", "criteria[0].level"},
+		},
+		{
+			name: "Duplicate and unknown criterion",
+			assessment: Assessment{
+				Feedback: "Bra",
+				Criteria: []CriterionAssessment{
+					{Criterion: "Avsändare", Level: "Tydlig", Justification: "A"},
+					{Criterion: "Avsändare", Level: "Tydlig"},
+					{Criterion: "Språk", Level: "Tydlig", Justification: "C"},
+				},
+			},
+			wantKeys: []string{"criteria", "criteria[1].criterion", "criteria[1].justification", "criteria[2].criterion"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateAssessment(v, testRubric(), &tt.assessment)
+			assert.Equal(t, len(v.Errors), len(tt.wantKeys))
+			for _, key := range tt.wantKeys {
+				_, ok := v.Errors[key]
+				assert.Equal(t, ok, true)
+			}
+		})
+	}
+}
+
+func TestRubricScore(t *testing.T) {
+	assessment := &Assessment{
+		Criteria: []CriterionAssessment{
+			{Criterion: "Avsändare", Level: "Tydlig"},
+			{Criterion: "Syfte", Level: "Delvis"},
+		},
+	}
+
+	testRubric().Score(assessment)
+
+	assert.Equal(t, assessment.Score, 3)
+	assert.Equal(t, assessment.MaxScore, 5)
+}
@@ -9,22 +9,40 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type SessionResponse struct {
-	ID                uuid.UUID `json:"id"`
-	ScenarioSessionID uuid.UUID `json:"scenario_session_id"`
-	SubmittedAt       time.Time `json:"submitted_at"`
-	RawAnswers        []byte    `json:"raw_answers"`
-	AIFeedback        []byte    `json:"ai_feedback"`
+	ID                 uuid.UUID        `json:"id"`
+	ScenarioSessionID  uuid.UUID        `json:"scenario_session_id"`
+	SubmittedAt        time.Time        `json:"submitted_at"`
+	RawAnswers         []byte           `json:"raw_answers"`
+	AIFeedback         []byte           `json:"ai_feedback"`
+	Grading            json.RawMessage  `json:"grading,omitempty"`
+	Score              *float64         `json:"score"`
+	MaxScore           *float64         `json:"max_score"`
+	FeedbackReleasedAt *time.Time       `json:"feedback_released_at"`
+	AISummary          *FeedbackSummary `json:"ai_summary,omitempty"`
+	FeedbackQuestions  []uuid.UUID      `json:"-"`
 }
 
 type SessionResponseOutput struct {
-	ID                uuid.UUID         `json:"id"`
-	ScenarioSessionID uuid.UUID         `json:"scenario_session_id"`
-	SubmittedAt       time.Time         `json:"submitted_at"`
-	RawAnswers        map[string]any    `json:"raw_answers,omitempty"`
-	AIFeedback        map[string]string `json:"ai_feedback,omitempty"`
+	ID                 uuid.UUID          `json:"id"`
+	ScenarioSessionID  uuid.UUID          `json:"scenario_session_id"`
+	SubmittedAt        time.Time          `json:"submitted_at"`
+	RawAnswers         map[string]any     `json:"raw_answers,omitempty"`
+	AIFeedback         map[string]string  `json:"ai_feedback,omitempty"`
+	Grading            json.RawMessage    `json:"grading,omitempty"`
+	Score              *float64           `json:"score"`
+	MaxScore           *float64           `json:"max_score"`
+	FeedbackHeld       bool               `json:"feedback_held"`
+	FeedbackReleasedAt *time.Time         `json:"feedback_released_at"`
+	FeedbackStatus     []ResponseFeedback `json:"feedback_status,omitempty"`
+	AISummary          *FeedbackSummary   `json:"ai_summary,omitempty"`
+}
+
+func (sr *SessionResponse) FeedbackHeld(session *ScenarioSession) bool {
+	return session.HoldFeedback && sr.FeedbackReleasedAt == nil
 }
 
 type SessionResponseModel struct {
@@ -33,12 +51,25 @@ type SessionResponseModel struct {
 
 func (sm *SessionResponseModel) Create(sr SessionResponse) (SessionResponse, error) {
 	query := `
-	INSERT INTO session_responses (scenario_session_id, raw_answers)
-	VALUES ($1, $2)
-	RETURNING id, submitted_at`
+	WITH response AS (
+		INSERT INTO session_responses (scenario_session_id, raw_answers, grading, score, max_score)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, submitted_at
+	), feedback AS (
+		INSERT INTO response_feedback (session_response_id, question_id)
+		SELECT response.id, question_id FROM response, unnest($6::uuid[]) AS question_id
+	), job AS (
+		INSERT INTO feedback_jobs (session_response_id)
+		SELECT id FROM response
+	)
+	SELECT id, submitted_at FROM response`
+	questionIDs := make([]string, len(sr.FeedbackQuestions))
+	for i, id := range sr.FeedbackQuestions {
+		questionIDs[i] = id.String()
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	err := sm.DB.QueryRowContext(ctx, query, sr.ScenarioSessionID, sr.RawAnswers).
+	err := sm.DB.QueryRowContext(ctx, query, sr.ScenarioSessionID, sr.RawAnswers, []byte(sr.Grading), sr.Score, sr.MaxScore, pq.Array(questionIDs)).
 		Scan(&sr.ID, &sr.SubmittedAt)
 	return sr, err
 }
@@ -50,7 +81,7 @@ func (sm *SessionResponseModel) AddFeedback(responseID uuid.UUID, feedback map[s
 	}
 	query := `
 	UPDATE session_responses
-	SET ai_feedback = $1
+	SET ai_feedback = COALESCE(ai_feedback, '{}'::jsonb) || $1::jsonb
 	WHERE id = $2`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -68,9 +99,30 @@ func (sm *SessionResponseModel) AddFeedback(responseID uuid.UUID, feedback map[s
 	return nil
 }
 
+func (sm *SessionResponseModel) SetSummary(responseID uuid.UUID, summary *FeedbackSummary, promptTemplateID uuid.NullUUID) error {
+	query := `
+	UPDATE session_responses
+	SET ai_summary = $1, summary_prompt_template_id = $2
+	WHERE id = $3`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := sm.DB.ExecContext(ctx, query, summary, promptTemplateID, responseID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
 func (sm *SessionResponseModel) Get(id uuid.UUID) (*SessionResponse, error) {
 	query := `
-	SELECT id, scenario_session_id, submitted_at, raw_answers, ai_feedback
+	SELECT id, scenario_session_id, submitted_at, raw_answers, ai_feedback, grading, score, max_score, feedback_released_at, ai_summary
 	FROM session_responses
 	WHERE id = $1`
 	var sr SessionResponse
@@ -82,6 +134,11 @@ func (sm *SessionResponseModel) Get(id uuid.UUID) (*SessionResponse, error) {
 		&sr.SubmittedAt,
 		&sr.RawAnswers,
 		&sr.AIFeedback,
+		&sr.Grading,
+		&sr.Score,
+		&sr.MaxScore,
+		&sr.FeedbackReleasedAt,
+		&sr.AISummary,
 	)
 	if err != nil {
 		switch {
@@ -96,7 +153,7 @@ func (sm *SessionResponseModel) Get(id uuid.UUID) (*SessionResponse, error) {
 
 func (sm *SessionResponseModel) GetAllByScenarioSessionID(scenarioSessionID uuid.UUID) ([]*SessionResponse, error) {
 	query := `
-	SELECT id, scenario_session_id, submitted_at, raw_answers, ai_feedback
+	SELECT id, scenario_session_id, submitted_at, raw_answers, ai_feedback, grading, score, max_score, feedback_released_at, ai_summary
 	FROM session_responses
 	WHERE scenario_session_id = $1
 	ORDER BY submitted_at DESC`
@@ -116,6 +173,11 @@ func (sm *SessionResponseModel) GetAllByScenarioSessionID(scenarioSessionID uuid
 			&sr.SubmittedAt,
 			&sr.RawAnswers,
 			&sr.AIFeedback,
+			&sr.Grading,
+			&sr.Score,
+			&sr.MaxScore,
+			&sr.FeedbackReleasedAt,
+			&sr.AISummary,
 		)
 		if err != nil {
 			return nil, err
@@ -128,3 +190,68 @@ func (sm *SessionResponseModel) GetAllByScenarioSessionID(scenarioSessionID uuid
 	}
 	return responses, nil
 }
+
+// GetTextAnswers returns every text answer to a question in a session, oldest
+// first, along with when the latest of them was submitted.
+func (sm *SessionResponseModel) GetTextAnswers(scenarioSessionID, questionID uuid.UUID) ([]string, time.Time, error) {
+	query := `
+	SELECT raw_answers ->> $2, submitted_at
+	FROM session_responses
+	WHERE scenario_session_id = $1 AND jsonb_typeof(raw_answers -> $2) = 'string'
+	ORDER BY submitted_at, id`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := sm.DB.QueryContext(ctx, query, scenarioSessionID, questionID.String())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+	var answers []string
+	var lastSubmittedAt time.Time
+	for rows.Next() {
+		var answer string
+		if err := rows.Scan(&answer, &lastSubmittedAt); err != nil {
+			return nil, time.Time{}, err
+		}
+		answers = append(answers, answer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+	return answers, lastSubmittedAt, nil
+}
+
+func (sm *SessionResponseModel) ReleaseFeedback(id uuid.UUID) (time.Time, error) {
+	query := `
+	UPDATE session_responses
+	SET feedback_released_at = COALESCE(feedback_released_at, now())
+	WHERE id = $1
+	RETURNING feedback_released_at`
+	var releasedAt time.Time
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := sm.DB.QueryRowContext(ctx, query, id).Scan(&releasedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return time.Time{}, ErrRecordNotFound
+		default:
+			return time.Time{}, err
+		}
+	}
+	return releasedAt, nil
+}
+
+func (sm *SessionResponseModel) ReleaseAllFeedback(scenarioSessionID uuid.UUID) (int64, error) {
+	query := `
+	UPDATE session_responses
+	SET feedback_released_at = now()
+	WHERE scenario_session_id = $1 AND feedback_released_at IS NULL`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := sm.DB.ExecContext(ctx, query, scenarioSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,99 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScenarioRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ScenarioID uuid.UUID `json:"scenario_id"`
+	Revision   int32     `json:"revision"`
+	Scenario   *Scenario `json:"scenario"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ScenarioRevisionModel struct {
+	DB *sql.DB
+}
+
+func (rm *ScenarioRevisionModel) Snapshot(scenario *Scenario) (*ScenarioRevision, error) {
+	content, err := json.Marshal(scenario)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	contentHash := hex.EncodeToString(sum[:])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := rm.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT id FROM scenarios WHERE id = $1 FOR UPDATE`, scenario.ID)
+	if err != nil {
+		return nil, err
+	}
+	revision := ScenarioRevision{ScenarioID: scenario.ID, Scenario: scenario}
+	var latestHash string
+	err = tx.QueryRowContext(ctx, `
+	SELECT id, revision, content_hash, created_at
+	FROM scenario_revisions
+	WHERE scenario_id = $1
+	ORDER BY revision DESC
+	LIMIT 1`, scenario.ID).Scan(&revision.ID, &revision.Revision, &latestHash, &revision.CreatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil && latestHash == contentHash {
+		return &revision, nil
+	}
+	err = tx.QueryRowContext(ctx, `
+	INSERT INTO scenario_revisions (scenario_id, revision, content, content_hash)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, revision, created_at`, scenario.ID, revision.Revision+1, content, contentHash).
+		Scan(&revision.ID, &revision.Revision, &revision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (rm *ScenarioRevisionModel) Get(id uuid.UUID) (*ScenarioRevision, error) {
+	query := `
+	SELECT id, scenario_id, revision, content, created_at
+	FROM scenario_revisions
+	WHERE id = $1`
+	var revision ScenarioRevision
+	var content []byte
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := rm.DB.QueryRowContext(ctx, query, id).Scan(
+		&revision.ID, &revision.ScenarioID, &revision.Revision, &content, &revision.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(content, &revision.Scenario); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
@@ -10,12 +10,15 @@ import (
 )
 
 type ScenarioSession struct {
-	ID         uuid.UUID `json:"id"`
-	Token      string    `json:"token"`
-	ScenarioID uuid.UUID `json:"scenario_id"`
-	Notes      string    `json:"notes"`
-	ExpiresAt  time.Time `json:"expires_at"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID                 uuid.UUID     `json:"id"`
+	Token              string        `json:"token"`
+	ScenarioID         uuid.UUID     `json:"scenario_id"`
+	ScenarioRevisionID uuid.NullUUID `json:"scenario_revision_id"`
+	Notes              string        `json:"notes"`
+	Language           Language      `json:"language"`
+	HoldFeedback       bool          `json:"hold_feedback"`
+	ExpiresAt          time.Time     `json:"expires_at"`
+	CreatedAt          time.Time     `json:"created_at"`
 }
 
 type ScenarioSessionModel struct {
@@ -24,10 +27,10 @@ type ScenarioSessionModel struct {
 
 func (sm *ScenarioSessionModel) Create(ss *ScenarioSession) error {
 	query := `
-	INSERT INTO scenario_sessions (token, scenario_id, notes, expires_at)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO scenario_sessions (token, scenario_id, scenario_revision_id, notes, language, hold_feedback, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
 	RETURNING id, created_at`
-	args := []any{ss.Token, ss.ScenarioID, ss.Notes, ss.ExpiresAt}
+	args := []any{ss.Token, ss.ScenarioID, ss.ScenarioRevisionID, ss.Notes, ss.Language, ss.HoldFeedback, ss.ExpiresAt}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	err := sm.DB.QueryRowContext(ctx, query, args...).Scan(&ss.ID, &ss.CreatedAt)
@@ -39,14 +42,14 @@ func (sm *ScenarioSessionModel) Create(ss *ScenarioSession) error {
 
 func (m *ScenarioSessionModel) Get(id uuid.UUID) (*ScenarioSession, error) {
 	query := `
-	SELECT id, scenario_id, token, notes, expires_at, created_at
+	SELECT id, scenario_id, scenario_revision_id, token, notes, language, hold_feedback, expires_at, created_at
 	FROM scenario_sessions
 	WHERE id = $1`
 	var s ScenarioSession
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&s.ID, &s.ScenarioID, &s.Token, &s.Notes, &s.ExpiresAt, &s.CreatedAt,
+		&s.ID, &s.ScenarioID, &s.ScenarioRevisionID, &s.Token, &s.Notes, &s.Language, &s.HoldFeedback, &s.ExpiresAt, &s.CreatedAt,
 	)
 	if err != nil {
 		switch {
@@ -61,14 +64,14 @@ func (m *ScenarioSessionModel) Get(id uuid.UUID) (*ScenarioSession, error) {
 
 func (sm *ScenarioSessionModel) GetByToken(token string) (ScenarioSession, error) {
 	query := `
-	SELECT id, token, scenario_id, notes, expires_at, created_at
+	SELECT id, token, scenario_id, scenario_revision_id, notes, language, hold_feedback, expires_at, created_at
 	FROM scenario_sessions
 	WHERE token = $1`
 	var s ScenarioSession
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	err := sm.DB.QueryRowContext(ctx, query, token).Scan(
-		&s.ID, &s.Token, &s.ScenarioID, &s.Notes, &s.ExpiresAt, &s.CreatedAt)
+		&s.ID, &s.Token, &s.ScenarioID, &s.ScenarioRevisionID, &s.Notes, &s.Language, &s.HoldFeedback, &s.ExpiresAt, &s.CreatedAt)
 	return s, err
 }
 
@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+type Permission string
+
+const (
+	PermissionNone  Permission = ""
+	PermissionView  Permission = "view"
+	PermissionEdit  Permission = "edit"
+	PermissionOwner Permission = "owner"
+)
+
+var permissionRank = map[Permission]int{
+	PermissionNone:  0,
+	PermissionView:  1,
+	PermissionEdit:  2,
+	PermissionOwner: 3,
+}
+
+func (p Permission) Includes(required Permission) bool {
+	return permissionRank[p] >= permissionRank[required]
+}
+
+type ScenarioShare struct {
+	ScenarioID uuid.UUID  `json:"scenario_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Email      string     `json:"email"`
+	Permission Permission `json:"permission"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ScenarioShareModel struct {
+	DB *sql.DB
+}
+
+func ValidateScenarioShare(v *validator.Validator, share *ScenarioShare) {
+	v.Check(validator.PermittedValues(share.Permission, PermissionView, PermissionEdit), "permission", "must be view or edit")
+}
+
+func (sm *ScenarioShareModel) Upsert(share *ScenarioShare) error {
+	query := `
+	INSERT INTO scenario_shares (scenario_id, user_id, permission)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (scenario_id, user_id) DO UPDATE SET permission = EXCLUDED.permission
+	RETURNING created_at`
+	args := []any{share.ScenarioID, share.UserID, share.Permission}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return sm.DB.QueryRowContext(ctx, query, args...).Scan(&share.CreatedAt)
+}
+
+func (sm *ScenarioShareModel) Delete(scenarioID, userID uuid.UUID) error {
+	query := `
+	DELETE FROM scenario_shares
+	WHERE scenario_id = $1 AND user_id = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := sm.DB.ExecContext(ctx, query, scenarioID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (sm *ScenarioShareModel) GetAllForScenario(scenarioID uuid.UUID) ([]ScenarioShare, error) {
+	query := `
+	SELECT sh.scenario_id, sh.user_id, u.email, sh.permission, sh.created_at
+	FROM scenario_shares sh
+	INNER JOIN users u ON u.id = sh.user_id
+	WHERE sh.scenario_id = $1
+	ORDER BY u.email`
+	shares := []ScenarioShare{}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := sm.DB.QueryContext(ctx, query, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var share ScenarioShare
+		if err := rows.Scan(&share.ScenarioID, &share.UserID, &share.Email, &share.Permission, &share.CreatedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// GetPermission works out what user may do with the scenario. Scenarios
+// without an owner, like those created before ownership existed, are owned by
+// every admin and can be viewed by everyone else.
+func (sm *ScenarioShareModel) GetPermission(scenarioID uuid.UUID, user *User) (Permission, error) {
+	query := `
+	SELECT s.owner_id, sh.permission
+	FROM scenarios s
+	LEFT JOIN scenario_shares sh ON sh.scenario_id = s.id AND sh.user_id = $2
+	WHERE s.id = $1`
+	var ownerID uuid.NullUUID
+	var shared sql.NullString
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := sm.DB.QueryRowContext(ctx, query, scenarioID, user.ID).Scan(&ownerID, &shared)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return PermissionNone, ErrRecordNotFound
+		default:
+			return PermissionNone, err
+		}
+	}
+	switch {
+	case ownerID.Valid && ownerID.UUID == user.ID, !ownerID.Valid && user.IsAdmin:
+		return PermissionOwner, nil
+	case shared.Valid:
+		return Permission(shared.String), nil
+	case !ownerID.Valid:
+		return PermissionView, nil
+	default:
+		return PermissionNone, nil
+	}
+}
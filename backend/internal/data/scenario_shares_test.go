@@ -0,0 +1,29 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+)
+
+func TestPermissionIncludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  Permission
+		required Permission
+		want     bool
+	}{
+		{name: "Owner can edit", granted: PermissionOwner, required: PermissionEdit, want: true},
+		{name: "Edit can view", granted: PermissionEdit, required: PermissionView, want: true},
+		{name: "Edit can't manage", granted: PermissionEdit, required: PermissionOwner, want: false},
+		{name: "View can't edit", granted: PermissionView, required: PermissionEdit, want: false},
+		{name: "None can't view", granted: PermissionNone, required: PermissionView, want: false},
+		{name: "Unknown can't view", granted: Permission("admin"), required: PermissionView, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.granted.Includes(tt.required), tt.want)
+		})
+	}
+}
@@ -4,20 +4,37 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"math/rand/v2"
+	"strings"
 	"time"
 
 	"github.com/berberapan/info-eval/internal/validator"
 	"github.com/google/uuid"
 )
 
+type ScenarioStatus string
+
+const (
+	ScenarioDraft     ScenarioStatus = "draft"
+	ScenarioPublished ScenarioStatus = "published"
+	ScenarioArchived  ScenarioStatus = "archived"
+)
+
+var ScenarioStatuses = []ScenarioStatus{ScenarioDraft, ScenarioPublished, ScenarioArchived}
+
 type Scenario struct {
-	ID          uuid.UUID  `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Difficulty  int16      `json:"difficulty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Exercises   []Exercise `json:"exercises"`
+	ID          uuid.UUID      `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Difficulty  int16          `json:"difficulty"`
+	Status      ScenarioStatus `json:"status"`
+	Language    Language       `json:"language"`
+	OwnerID     uuid.NullUUID  `json:"owner_id"`
+	ClonedFrom  uuid.NullUUID  `json:"cloned_from"`
+	Version     int32          `json:"version"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Exercises   []Exercise     `json:"exercises"`
 }
 
 type ScenarioModel struct {
@@ -34,18 +51,65 @@ func ValidateScenario(v *validator.Validator, scenario *Scenario) {
 
 	v.Check(scenario.Difficulty <= 5, "difficulty", "must be 5 or smaller")
 	v.Check(scenario.Difficulty >= 1, "difficulty", "must be 1 or bigger")
+
+	v.Check(validator.PermittedValues(scenario.Status, ScenarioStatuses...), "status", "must be draft, published or archived")
+
+	ValidateLanguage(v, "language", scenario.Language)
+}
+
+func (s *Scenario) QuestionMap() map[uuid.UUID]ExerciseQuestion {
+	questions := make(map[uuid.UUID]ExerciseQuestion)
+	for _, e := range s.Exercises {
+		for _, q := range e.Questions {
+			questions[q.ID] = q
+		}
+	}
+	return questions
+}
+
+// ForStudent returns a copy of the scenario that doesn't give away the
+// answers to ordering and matching questions. Ordering options come in a
+// random order, and matching options lose their match text, which is listed
+// on its own and shuffled as the question's match targets.
+func (s *Scenario) ForStudent() *Scenario {
+	student := *s
+	student.Exercises = make([]Exercise, len(s.Exercises))
+	for i, e := range s.Exercises {
+		e.Questions = append([]ExerciseQuestion(nil), e.Questions...)
+		for j := range e.Questions {
+			q := &e.Questions[j]
+			switch q.ExerciseType {
+			case OrderingType:
+				q.Options = append([]QuestionOption(nil), q.Options...)
+				rand.Shuffle(len(q.Options), func(a, b int) { q.Options[a], q.Options[b] = q.Options[b], q.Options[a] })
+				for k := range q.Options {
+					q.Options[k].Order = int16(k + 1)
+				}
+			case MatchingType:
+				q.Options = append([]QuestionOption(nil), q.Options...)
+				q.MatchTargets = make([]string, len(q.Options))
+				for k := range q.Options {
+					q.MatchTargets[k] = q.Options[k].MatchText
+					q.Options[k].MatchText = ""
+				}
+				rand.Shuffle(len(q.MatchTargets), func(a, b int) { q.MatchTargets[a], q.MatchTargets[b] = q.MatchTargets[b], q.MatchTargets[a] })
+			}
+		}
+		student.Exercises[i] = e
+	}
+	return &student
 }
 
 func (sm *ScenarioModel) Get(id uuid.UUID) (*Scenario, error) {
 	query := `
-	SELECT id, title, description, difficulty, created_at, updated_at
+	SELECT id, title, description, difficulty, status, language, owner_id, cloned_from, version, created_at, updated_at
 	FROM scenarios
 	WHERE id = $1`
 	var s Scenario
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	err := sm.DB.QueryRowContext(ctx, query, id).Scan(
-		&s.ID, &s.Title, &s.Description, &s.Difficulty, &s.CreatedAt, &s.UpdatedAt,
+		&s.ID, &s.Title, &s.Description, &s.Difficulty, &s.Status, &s.Language, &s.OwnerID, &s.ClonedFrom, &s.Version, &s.CreatedAt, &s.UpdatedAt,
 	)
 	if err != nil {
 		switch {
@@ -81,21 +145,49 @@ func (sm *ScenarioModel) Get(id uuid.UUID) (*Scenario, error) {
 	return &s, nil
 }
 
-func (sm *ScenarioModel) GetAll() ([]*Scenario, error) {
+func (sm *ScenarioModel) GetAllByStatus(status ScenarioStatus) ([]*Scenario, error) {
+	query := `
+	SELECT id, title, description, difficulty, status, language, owner_id, cloned_from, version, created_at, updated_at
+	FROM scenarios
+	WHERE status = $1`
+	scenarios := []*Scenario{}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := sm.DB.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s Scenario
+		if err := rows.Scan(&s.ID, &s.Title, &s.Description, &s.Difficulty, &s.Status, &s.Language, &s.OwnerID, &s.ClonedFrom, &s.Version, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, &s)
+	}
+	return scenarios, nil
+}
+
+func (sm *ScenarioModel) GetAllForUser(userID uuid.UUID, status ScenarioStatus) ([]*Scenario, error) {
 	query := `
-	SELECT id, title, description, difficulty, created_at, updated_at
-	FROM scenarios`
+	SELECT id, title, description, difficulty, status, language, owner_id, cloned_from, version, created_at, updated_at
+	FROM scenarios
+	WHERE status = $2 AND (
+		owner_id = $1
+		OR EXISTS (SELECT 1 FROM scenario_shares sh WHERE sh.scenario_id = scenarios.id AND sh.user_id = $1)
+	)
+	ORDER BY updated_at DESC`
 	scenarios := []*Scenario{}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	rows, err := sm.DB.QueryContext(ctx, query)
+	rows, err := sm.DB.QueryContext(ctx, query, userID, status)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var s Scenario
-		if err := rows.Scan(&s.ID, &s.Title, &s.Description, &s.Difficulty, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.Title, &s.Description, &s.Difficulty, &s.Status, &s.Language, &s.OwnerID, &s.ClonedFrom, &s.Version, &s.CreatedAt, &s.UpdatedAt); err != nil {
 			return nil, err
 		}
 		scenarios = append(scenarios, &s)
@@ -105,11 +197,69 @@ func (sm *ScenarioModel) GetAll() ([]*Scenario, error) {
 
 func (sm *ScenarioModel) Insert(scenario *Scenario) error {
 	query := `
-	INSERT INTO scenarios (title, description, difficulty)
-	VALUES ($1, $2, $3)
-	RETURNING id, created_at, updated_at`
-	args := []any{scenario.Title, scenario.Description, scenario.Difficulty}
+	INSERT INTO scenarios (title, description, difficulty, status, language, owner_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, version, created_at, updated_at`
+	args := []any{scenario.Title, scenario.Description, scenario.Difficulty, scenario.Status, scenario.Language, scenario.OwnerID}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return sm.DB.QueryRowContext(ctx, query, args...).Scan(&scenario.ID, &scenario.Version, &scenario.CreatedAt, &scenario.UpdatedAt)
+}
+
+func (sm *ScenarioModel) Update(scenario *Scenario) error {
+	query := `
+	UPDATE scenarios
+	SET title = $1, description = $2, difficulty = $3, status = $4, language = $5, version = version + 1, updated_at = now()
+	WHERE id = $6 AND version = $7
+	RETURNING version, updated_at`
+	args := []any{scenario.Title, scenario.Description, scenario.Difficulty, scenario.Status, scenario.Language, scenario.ID, scenario.Version}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := sm.DB.QueryRowContext(ctx, query, args...).Scan(&scenario.Version, &scenario.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a scenario that has never been run. Once it has sessions
+// the students' work depends on it, and it can only be archived.
+func (sm *ScenarioModel) Delete(id uuid.UUID, version int32) error {
+	query := `
+	DELETE FROM scenarios
+	WHERE id = $1 AND version = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := sm.DB.ExecContext(ctx, query, id, version)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `violates foreign key constraint "scenario_sessions_scenario_id_fkey"`):
+			return ErrScenarioInUse
+		default:
+			return err
+		}
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
+
+func (sm *ScenarioModel) Exists(id uuid.UUID) (bool, error) {
+	query := `
+	SELECT EXISTS (SELECT 1 FROM scenarios WHERE id = $1)`
+	var exists bool
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	return sm.DB.QueryRowContext(ctx, query, args...).Scan(&scenario.ID, &scenario.CreatedAt, &scenario.UpdatedAt)
+	err := sm.DB.QueryRowContext(ctx, query, id).Scan(&exists)
+	return exists, err
 }
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/google/uuid"
+)
+
+func TestScenarioForStudent(t *testing.T) {
+	var ordering []QuestionOption
+	for i := range 20 {
+		ordering = append(ordering, QuestionOption{ID: uuid.New(), OptionText: string(rune('a' + i)), Order: int16(i + 1)})
+	}
+	matching := []QuestionOption{
+		{ID: uuid.New(), OptionText: "SVT", MatchText: "Public service"},
+		{ID: uuid.New(), OptionText: "Aftonbladet", MatchText: "Kvällstidning"},
+	}
+	scenario := &Scenario{
+		Exercises: []Exercise{
+			{
+				Questions: []ExerciseQuestion{
+					{ID: uuid.New(), ExerciseType: OrderingType, Options: ordering},
+					{ID: uuid.New(), ExerciseType: MatchingType, Options: matching},
+				},
+			},
+		},
+	}
+
+	student := scenario.ForStudent()
+
+	shuffled := student.Exercises[0].Questions[0].Options
+	assert.Equal(t, len(shuffled), len(ordering))
+	assert.Equal(t, slices.EqualFunc(shuffled, ordering, func(a, b QuestionOption) bool { return a.ID == b.ID }), false)
+	for i, o := range shuffled {
+		assert.Equal(t, o.Order, int16(i+1))
+	}
+	matched := student.Exercises[0].Questions[1]
+	for _, o := range matched.Options {
+		assert.Equal(t, o.MatchText, "")
+	}
+	targets := slices.Sorted(slices.Values(matched.MatchTargets))
+	assert.Equal(t, slices.Equal(targets, []string{"Kvällstidning", "Public service"}), true)
+
+	assert.Equal(t, scenario.Exercises[0].Questions[0].Options[0].ID, ordering[0].ID)
+	assert.Equal(t, scenario.Exercises[0].Questions[1].Options[0].MatchText, "Public service")
+	assert.Equal(t, len(scenario.Exercises[0].Questions[1].MatchTargets), 0)
+}
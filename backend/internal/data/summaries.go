@@ -0,0 +1,46 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+type FeedbackSummary struct {
+	Strengths  []string `json:"strengths"`
+	Weaknesses []string `json:"weaknesses"`
+	NextStep   string   `json:"next_step"`
+}
+
+func (s *FeedbackSummary) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *FeedbackSummary) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("summary must be scanned from JSON")
+	}
+	return json.Unmarshal(b, s)
+}
+
+func ValidateFeedbackSummary(v *validator.Validator, s *FeedbackSummary) {
+	v.Check(len(s.Strengths) >= 1, "strengths", "must contain at least one strength")
+	v.Check(len(s.Strengths) <= 3, "strengths", "can't contain more than 3 strengths")
+	v.Check(len(s.Weaknesses) <= 3, "weaknesses", "can't contain more than 3 weaknesses")
+	for key, items := range map[string][]string{"strengths": s.Strengths, "weaknesses": s.Weaknesses} {
+		for i, item := range items {
+			itemKey := fmt.Sprintf("%s[%d]", key, i)
+			v.Check(item != "", itemKey, "must be provided")
+			v.Check(len(item) <= 500, itemKey, "can't exceed 500 chars")
+		}
+	}
+	v.Check(s.NextStep != "", "next_step", "must be provided")
+	v.Check(len(s.NextStep) <= 500, "next_step", "can't exceed 500 chars")
+}
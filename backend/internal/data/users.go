@@ -21,6 +21,7 @@ type User struct {
 	ID        uuid.UUID `json:"id"`
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
+	IsAdmin   bool      `json:"is_admin"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -104,7 +105,7 @@ func (um *UserModel) Insert(user *User) error {
 
 func (um *UserModel) Get(id uuid.UUID) (*User, error) {
 	query := `
-	SELECT id, email, password_hash, created_at, updated_at
+	SELECT id, email, password_hash, is_admin, created_at, updated_at
 	FROM users
 	WHERE id = $1`
 	var user User
@@ -114,6 +115,7 @@ func (um *UserModel) Get(id uuid.UUID) (*User, error) {
 		&user.ID,
 		&user.Email,
 		&user.Password.hash,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -131,7 +133,7 @@ func (um *UserModel) Get(id uuid.UUID) (*User, error) {
 
 func (um *UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-	SELECT id, email, password_hash, created_at, updated_at
+	SELECT id, email, password_hash, is_admin, created_at, updated_at
 	FROM users
 	WHERE email = $1`
 	var user User
@@ -141,6 +143,7 @@ func (um *UserModel) GetByEmail(email string) (*User, error) {
 		&user.ID,
 		&user.Email,
 		&user.Password.hash,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
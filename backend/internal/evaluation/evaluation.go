@@ -0,0 +1,391 @@
+package evaluation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/feedback"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+type Verdict string
+
+const (
+	VerdictCorrect   Verdict = "correct"
+	VerdictPartial   Verdict = "partial"
+	VerdictIncorrect Verdict = "incorrect"
+)
+
+var Verdicts = []Verdict{VerdictCorrect, VerdictPartial, VerdictIncorrect}
+
+const (
+	CheckLanguage  = "language"
+	CheckLength    = "length"
+	CheckForbidden = "forbidden_phrases"
+	CheckRubric    = "rubric_agreement"
+	CheckVerdict   = "verdict"
+)
+
+type Checks struct {
+	Language         bool     `json:"language"`
+	MinChars         int      `json:"min_chars"`
+	MaxChars         int      `json:"max_chars"`
+	ForbiddenPhrases []string `json:"forbidden_phrases"`
+	RubricAgreement  bool     `json:"rubric_agreement"`
+	Verdict          bool     `json:"verdict"`
+}
+
+type Case struct {
+	ID               string            `json:"id"`
+	Question         string            `json:"question"`
+	PromptGuidance   string            `json:"prompt_guidance"`
+	Rubric           *data.Rubric      `json:"rubric"`
+	Language         data.Language     `json:"language"`
+	Answer           string            `json:"answer"`
+	ExpectedVerdict  Verdict           `json:"expected_verdict"`
+	ExpectedLevels   map[string]string `json:"expected_levels"`
+	ForbiddenPhrases []string          `json:"forbidden_phrases"`
+}
+
+type Dataset struct {
+	Checks Checks `json:"checks"`
+	Cases  []Case `json:"cases"`
+}
+
+type CheckResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type CaseResult struct {
+	ID         string           `json:"id"`
+	Feedback   string           `json:"feedback,omitempty"`
+	Assessment *data.Assessment `json:"assessment,omitempty"`
+	Verdict    Verdict          `json:"verdict,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Checks     []CheckResult    `json:"checks"`
+}
+
+func (r *CaseResult) Passed() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *CaseResult) check(name string) *CheckResult {
+	for i := range r.Checks {
+		if r.Checks[i].Name == name {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+type Run struct {
+	Provider  string                     `json:"provider"`
+	Templates map[data.PromptKind]string `json:"templates"`
+	StartedAt time.Time                  `json:"started_at"`
+	Duration  string                     `json:"duration"`
+	Cases     []CaseResult               `json:"cases"`
+}
+
+type CheckSummary struct {
+	Passed int `json:"passed"`
+	Total  int `json:"total"`
+}
+
+type Summary struct {
+	Cases  int                     `json:"cases"`
+	Passed int                     `json:"passed"`
+	Errors int                     `json:"errors"`
+	Checks map[string]CheckSummary `json:"checks"`
+}
+
+func (run *Run) Summary() Summary {
+	summary := Summary{Cases: len(run.Cases), Checks: make(map[string]CheckSummary)}
+	for _, c := range run.Cases {
+		if c.Passed() {
+			summary.Passed++
+		}
+		if c.Error != "" {
+			summary.Errors++
+		}
+		for _, check := range c.Checks {
+			s := summary.Checks[check.Name]
+			s.Total++
+			if check.Passed {
+				s.Passed++
+			}
+			summary.Checks[check.Name] = s
+		}
+	}
+	return summary
+}
+
+func ValidateDataset(v *validator.Validator, d *Dataset) {
+	v.Check(len(d.Cases) > 0, "cases", "must contain at least one case")
+	v.Check(d.Checks.MinChars >= 0, "checks.min_chars", "can't be negative")
+	v.Check(d.Checks.MaxChars >= 0, "checks.max_chars", "can't be negative")
+	v.Check(d.Checks.MaxChars == 0 || d.Checks.MaxChars >= d.Checks.MinChars, "checks.max_chars", "can't be less than min_chars")
+
+	ids := make([]string, 0, len(d.Cases))
+	for i, c := range d.Cases {
+		key := fmt.Sprintf("cases[%d]", i)
+		ids = append(ids, c.ID)
+		v.Check(c.ID != "", key+".id", "must be provided")
+		v.Check(c.Answer != "", key+".answer", "must be provided")
+		data.ValidateLanguage(v, key+".language", c.Language.OrDefault())
+		nested := validator.New()
+		data.ValidateExerciseQuestion(nested, c.question())
+		for k, message := range nested.Errors {
+			v.AddError(key+"."+k, message)
+		}
+		if c.ExpectedVerdict != "" {
+			v.Check(validator.PermittedValues(c.ExpectedVerdict, Verdicts...), key+".expected_verdict", "must be correct, partial or incorrect")
+			v.Check(c.Rubric != nil, key+".expected_verdict", "needs a rubric to compare against")
+		}
+		if len(c.ExpectedLevels) > 0 {
+			if c.Rubric == nil {
+				v.AddError(key+".expected_levels", "needs a rubric to compare against")
+				continue
+			}
+			for criterion, level := range c.ExpectedLevels {
+				ci := slices.IndexFunc(c.Rubric.Criteria, func(rc data.RubricCriterion) bool { return rc.Name == criterion })
+				if ci < 0 {
+					v.AddError(key+".expected_levels", fmt.Sprintf("unknown criterion %q", criterion))
+					continue
+				}
+				ok := slices.ContainsFunc(c.Rubric.Criteria[ci].Levels, func(l data.RubricLevel) bool { return l.Label == level })
+				v.Check(ok, key+".expected_levels", fmt.Sprintf("unknown level %q for criterion %q", level, criterion))
+			}
+		}
+	}
+	v.Check(validator.Unique(ids), "cases", "must have unique ids")
+}
+
+func (c *Case) question() *data.ExerciseQuestion {
+	return &data.ExerciseQuestion{
+		ID:             uuid.NewSHA1(uuid.NameSpaceOID, []byte(c.ID)),
+		ExerciseType:   data.FreeTextType,
+		Question:       c.Question,
+		PromptGuidance: sql.NullString{String: c.PromptGuidance, Valid: c.PromptGuidance != ""},
+		Rubric:         c.Rubric,
+	}
+}
+
+// Evaluate runs every case through the same generator the API uses. Cases run
+// in parallel, so the generator's provider should carry its own limits.
+func Evaluate(ctx context.Context, generator *feedback.Generator, templates map[data.PromptKind]*data.PromptTemplate, d *Dataset) []CaseResult {
+	results := make([]CaseResult, len(d.Cases))
+	var wg sync.WaitGroup
+	for i, c := range d.Cases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			question := c.question()
+			template := templates[data.PromptFreeText]
+			if question.Rubric != nil {
+				template = templates[data.PromptRubric]
+			}
+			result, err := generator.Generate(ctx, template, c.Language.OrDefault(), *question, c.Answer)
+			if err != nil {
+				results[i] = CaseResult{ID: c.ID, Error: err.Error(), Checks: []CheckResult{}}
+				return
+			}
+			results[i] = Score(&d.Checks, &c, result)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func Score(checks *Checks, c *Case, result *feedback.Result) CaseResult {
+	r := CaseResult{ID: c.ID, Feedback: result.Text, Assessment: result.Assessment, Checks: []CheckResult{}}
+	if result.Assessment != nil {
+		r.Verdict = AssessmentVerdict(result.Assessment)
+	}
+
+	if checks.Language {
+		want := c.Language.OrDefault()
+		got := DetectLanguage(result.Text)
+		r.Checks = append(r.Checks, CheckResult{Name: CheckLanguage, Passed: got == want, Detail: fmt.Sprintf("want %s, detected %q", want, got)})
+	}
+	if checks.MinChars > 0 || checks.MaxChars > 0 {
+		n := utf8.RuneCountInString(result.Text)
+		passed := n >= checks.MinChars && (checks.MaxChars == 0 || n <= checks.MaxChars)
+		r.Checks = append(r.Checks, CheckResult{Name: CheckLength, Passed: passed, Detail: fmt.Sprintf("%d chars", n)})
+	}
+	if phrases := append(slices.Clone(checks.ForbiddenPhrases), c.ForbiddenPhrases...); len(phrases) > 0 {
+		text := strings.ToLower(result.Text)
+		var found []string
+		for _, phrase := range phrases {
+			if strings.Contains(text, strings.ToLower(phrase)) {
+				found = append(found, fmt.Sprintf("%q", phrase))
+			}
+		}
+		r.Checks = append(r.Checks, CheckResult{Name: CheckForbidden, Passed: len(found) == 0, Detail: strings.Join(found, ", ")})
+	}
+	if checks.RubricAgreement && len(c.ExpectedLevels) > 0 && result.Assessment != nil {
+		var mismatches []string
+		for _, ca := range result.Assessment.Criteria {
+			if want, ok := c.ExpectedLevels[ca.Criterion]; ok && want != ca.Level {
+				mismatches = append(mismatches, fmt.Sprintf("%s: want %s, got %s", ca.Criterion, want, ca.Level))
+			}
+		}
+		slices.Sort(mismatches)
+		agreed := len(c.ExpectedLevels) - len(mismatches)
+		detail := fmt.Sprintf("%d/%d criteria agree", agreed, len(c.ExpectedLevels))
+		if len(mismatches) > 0 {
+			detail += "; " + strings.Join(mismatches, "; ")
+		}
+		r.Checks = append(r.Checks, CheckResult{Name: CheckRubric, Passed: len(mismatches) == 0, Detail: detail})
+	}
+	if checks.Verdict && c.ExpectedVerdict != "" && result.Assessment != nil {
+		r.Checks = append(r.Checks, CheckResult{Name: CheckVerdict, Passed: r.Verdict == c.ExpectedVerdict, Detail: fmt.Sprintf("want %s, got %s", c.ExpectedVerdict, r.Verdict)})
+	}
+	return r
+}
+
+func AssessmentVerdict(a *data.Assessment) Verdict {
+	switch {
+	case a.MaxScore > 0 && a.Score >= a.MaxScore:
+		return VerdictCorrect
+	case a.Score <= 0:
+		return VerdictIncorrect
+	default:
+		return VerdictPartial
+	}
+}
+
+var stopwords = map[data.Language][]string{
+	data.LanguageSwedish: {"och", "är", "att", "det", "som", "en", "på", "inte", "för", "med", "du", "har", "av", "den", "till", "om", "kan", "men", "din", "ditt", "vad", "bra", "också"},
+	data.LanguageEnglish: {"the", "and", "is", "to", "of", "that", "it", "you", "for", "with", "not", "are", "this", "your", "can", "but", "what", "good", "also", "be"},
+}
+
+// DetectLanguage is a rough guess based on common words, which is enough to
+// notice feedback coming back in the wrong language. It returns an empty
+// language when the text gives no clue.
+func DetectLanguage(text string) data.Language {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	var best data.Language
+	bestScore := 0
+	for _, language := range data.Languages {
+		score := 0
+		for _, word := range words {
+			if slices.Contains(stopwords[language], word) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = language, score
+		} else if score == bestScore {
+			best = ""
+		}
+	}
+	return best
+}
+
+type Change struct {
+	CaseID string `json:"case_id"`
+	Check  string `json:"check"`
+	Base   string `json:"base"`
+	Head   string `json:"head"`
+}
+
+type Comparison struct {
+	Base         Summary  `json:"base"`
+	Head         Summary  `json:"head"`
+	Regressions  []Change `json:"regressions"`
+	Improvements []Change `json:"improvements"`
+	Added        []string `json:"added"`
+	Removed      []string `json:"removed"`
+}
+
+func outcome(r *CaseResult, check string) (string, bool) {
+	c := r.check(check)
+	switch {
+	case c == nil:
+		return "skipped", false
+	case c.Passed:
+		return "pass", true
+	default:
+		return "fail: " + c.Detail, false
+	}
+}
+
+func generated(r *CaseResult) string {
+	if r.Error != "" {
+		return "error: " + r.Error
+	}
+	return "ok"
+}
+
+// Diff compares two runs case by case. A check counts as a regression when it
+// passed in base and doesn't in head, and as an improvement the other way round.
+func Diff(base, head *Run) *Comparison {
+	comparison := &Comparison{Base: base.Summary(), Head: head.Summary()}
+	baseCases := make(map[string]*CaseResult)
+	for i := range base.Cases {
+		baseCases[base.Cases[i].ID] = &base.Cases[i]
+	}
+	for i := range head.Cases {
+		h := &head.Cases[i]
+		b, ok := baseCases[h.ID]
+		if !ok {
+			comparison.Added = append(comparison.Added, h.ID)
+			continue
+		}
+		delete(baseCases, h.ID)
+
+		if b.Error != "" || h.Error != "" {
+			change := Change{CaseID: h.ID, Check: "generate", Base: generated(b), Head: generated(h)}
+			switch {
+			case b.Error == "" && h.Error != "":
+				comparison.Regressions = append(comparison.Regressions, change)
+			case b.Error != "" && h.Error == "":
+				comparison.Improvements = append(comparison.Improvements, change)
+			}
+			continue
+		}
+		var names []string
+		for _, c := range append(slices.Clone(b.Checks), h.Checks...) {
+			if !slices.Contains(names, c.Name) {
+				names = append(names, c.Name)
+			}
+		}
+		for _, name := range names {
+			baseOutcome, basePassed := outcome(b, name)
+			headOutcome, headPassed := outcome(h, name)
+			change := Change{CaseID: h.ID, Check: name, Base: baseOutcome, Head: headOutcome}
+			switch {
+			case basePassed && !headPassed:
+				comparison.Regressions = append(comparison.Regressions, change)
+			case !basePassed && headPassed:
+				comparison.Improvements = append(comparison.Improvements, change)
+			}
+		}
+	}
+	for id := range baseCases {
+		comparison.Removed = append(comparison.Removed, id)
+	}
+	slices.Sort(comparison.Removed)
+	return comparison
+}
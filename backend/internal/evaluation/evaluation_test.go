@@ -0,0 +1,132 @@
+package evaluation
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/feedback"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+var sourceRubric = &data.Rubric{Criteria: []data.RubricCriterion{
+	{Name: "Avsändare", Levels: []data.RubricLevel{{Label: "Saknas", Descriptor: "-"}, {Label: "Tydlig", Points: 1, Descriptor: "-"}}},
+	{Name: "Syfte", Levels: []data.RubricLevel{{Label: "Saknas", Descriptor: "-"}, {Label: "Tydlig", Points: 1, Descriptor: "-"}}},
+}}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want data.Language
+	}{
+		{name: "Swedish", text: "Bra svar! Du har hittat avsändaren och det är viktigt.", want: data.LanguageSwedish},
+		{name: "English", text: "Good answer! You found the sender and that is important.", want: data.LanguageEnglish},
+		{name: "No clue", text: "SVT", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, DetectLanguage(tt.text), tt.want)
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	checks := &Checks{Language: true, MinChars: 10, MaxChars: 80, ForbiddenPhrases: []string{"som en AI"}, RubricAgreement: true, Verdict: true}
+
+	t.Run("Free text", func(t *testing.T) {
+		c := &Case{ID: "nej", Language: data.LanguageSwedish, ForbiddenPhrases: []string{"rätt svar"}}
+		r := Score(checks, c, &feedback.Result{Text: "Som en AI kan jag säga att det är rätt svar."})
+
+		assert.Equal(t, len(r.Checks), 3)
+		assert.Equal(t, r.check(CheckLanguage).Passed, true)
+		assert.Equal(t, r.check(CheckLength).Passed, true)
+		assert.Equal(t, r.check(CheckForbidden).Passed, false)
+		assert.StringContains(t, r.check(CheckForbidden).Detail, `"rätt svar"`)
+		assert.Equal(t, r.Passed(), false)
+	})
+
+	t.Run("Rubric", func(t *testing.T) {
+		c := &Case{ID: "avsändare", Rubric: sourceRubric, ExpectedVerdict: VerdictCorrect, ExpectedLevels: map[string]string{"Avsändare": "Tydlig", "Syfte": "Tydlig"}}
+		assessment := &data.Assessment{
+			Criteria: []data.CriterionAssessment{{Criterion: "Avsändare", Level: "Tydlig"}, {Criterion: "Syfte", Level: "Saknas"}},
+			Feedback: "Du har hittat avsändaren men inte syftet med texten.",
+			Score:    1,
+			MaxScore: 2,
+		}
+		r := Score(checks, c, &feedback.Result{Text: assessment.Feedback, Assessment: assessment})
+
+		assert.Equal(t, r.Verdict, VerdictPartial)
+		assert.Equal(t, r.check(CheckRubric).Passed, false)
+		assert.StringContains(t, r.check(CheckRubric).Detail, "1/2 criteria agree; Syfte: want Tydlig, got Saknas")
+		assert.Equal(t, r.check(CheckVerdict).Passed, false)
+	})
+}
+
+func TestValidateDataset(t *testing.T) {
+	d := &Dataset{
+		Checks: Checks{MinChars: 50, MaxChars: 10},
+		Cases: []Case{
+			{ID: "a", Question: "Q", Answer: "A", ExpectedVerdict: VerdictCorrect},
+			{ID: "a", Question: "Q", Answer: "A", Language: "fi", Rubric: sourceRubric, ExpectedLevels: map[string]string{"Avsändare": "Bra"}},
+		},
+	}
+	v := validator.New()
+	ValidateDataset(v, d)
+
+	for _, key := range []string{"checks.max_chars", "cases", "cases[0].expected_verdict", "cases[1].language", "cases[1].expected_levels"} {
+		_, ok := v.Errors[key]
+		assert.Equal(t, ok, true)
+	}
+	assert.Equal(t, len(v.Errors), 5)
+}
+
+func TestEvaluate(t *testing.T) {
+	fake := llm.NewFake()
+	fake.Replies = []string{`{"criteria":[{"criterion":"Avsändare","level":"Tydlig","justification":"SVT"},{"criterion":"Syfte","level":"Tydlig","justification":"Nyheter"}],"feedback":"Bra, du har hittat både avsändare och syfte."}`}
+	generator := &feedback.Generator{LLM: fake, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	d := &Dataset{
+		Checks: Checks{RubricAgreement: true, Verdict: true},
+		Cases:  []Case{{ID: "rubric", Question: "Vem är avsändaren?", Answer: "SVT", Rubric: sourceRubric, ExpectedVerdict: VerdictCorrect, ExpectedLevels: map[string]string{"Avsändare": "Tydlig"}}},
+	}
+
+	results := Evaluate(context.Background(), generator, data.DefaultPromptTemplates, d)
+
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Error, "")
+	assert.Equal(t, results[0].Verdict, VerdictCorrect)
+	assert.Equal(t, results[0].Passed(), true)
+}
+
+func TestDiff(t *testing.T) {
+	base := &Run{Cases: []CaseResult{
+		{ID: "a", Checks: []CheckResult{{Name: CheckLength, Passed: true}, {Name: CheckLanguage, Passed: false, Detail: "want sv"}}},
+		{ID: "b", Checks: []CheckResult{{Name: CheckLength, Passed: true}}},
+		{ID: "c", Error: "timeout"},
+		{ID: "d"},
+	}}
+	head := &Run{Cases: []CaseResult{
+		{ID: "a", Checks: []CheckResult{{Name: CheckLength, Passed: false, Detail: "700 chars"}, {Name: CheckLanguage, Passed: true}}},
+		{ID: "b", Error: "rejected"},
+		{ID: "c", Checks: []CheckResult{{Name: CheckLength, Passed: true}}},
+		{ID: "e"},
+	}}
+
+	c := Diff(base, head)
+
+	assert.Equal(t, len(c.Regressions), 2)
+	assert.Equal(t, c.Regressions[0], Change{CaseID: "a", Check: CheckLength, Base: "pass", Head: "fail: 700 chars"})
+	assert.Equal(t, c.Regressions[1], Change{CaseID: "b", Check: "generate", Base: "ok", Head: "error: rejected"})
+	assert.Equal(t, len(c.Improvements), 2)
+	assert.Equal(t, c.Improvements[0].Check, CheckLanguage)
+	assert.Equal(t, c.Improvements[1].CaseID, "c")
+	assert.Equal(t, len(c.Added), 1)
+	assert.Equal(t, c.Removed[0], "d")
+	assert.Equal(t, c.Base.Errors, 1)
+	assert.Equal(t, c.Head.Passed, 2)
+}
@@ -0,0 +1,123 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+var questionDraftsSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"questions": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"type":            {Type: llm.TypeString, Enum: exerciseTypeNames()},
+					"question":        {Type: llm.TypeString},
+					"prompt_guidance": {Type: llm.TypeString},
+					"options": {
+						Type: llm.TypeArray,
+						Items: &llm.Schema{
+							Type: llm.TypeObject,
+							Properties: map[string]*llm.Schema{
+								"option_text": {Type: llm.TypeString},
+								"is_correct":  {Type: llm.TypeBoolean},
+								"feedback":    {Type: llm.TypeString},
+								"match_text":  {Type: llm.TypeString},
+							},
+							Required: []string{"option_text", "is_correct", "feedback", "match_text"},
+						},
+					},
+				},
+				Required: []string{"type", "question", "prompt_guidance", "options"},
+			},
+		},
+	},
+	Required: []string{"questions"},
+}
+
+func exerciseTypeNames() []string {
+	names := make([]string, len(data.ExerciseTypes))
+	for i, t := range data.ExerciseTypes {
+		names[i] = string(t)
+	}
+	return names
+}
+
+// DraftQuestions proposes one question per requested type from an exercise's
+// material. The material is written by the teacher but often quotes the
+// sources being examined, so it's left out of the prompt the replies are
+// checked against for leaks.
+func (g *Generator) DraftQuestions(ctx context.Context, template *data.PromptTemplate, language data.Language, info string, media []data.PromptMedia, types []data.ExerciseType) ([]data.BundleQuestion, error) {
+	vars := data.PromptVars{Language: language.Name(), Info: info, Media: media, Types: types}
+	prompt, err := template.Render(vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	vars.Info = ""
+	instructions, err := template.Render(vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	var questions []data.BundleQuestion
+	err = g.generateJSON(ctx, "question drafts", prompt, questionDraftsSchema, func(output string) error {
+		questions, err = parseQuestionDrafts(output, types)
+		if err != nil {
+			return err
+		}
+		return checkLeaks(instructions, questionDraftTexts(questions)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return questions, nil
+}
+
+func parseQuestionDrafts(output string, types []data.ExerciseType) ([]data.BundleQuestion, error) {
+	var drafts struct {
+		Questions []data.BundleQuestion `json:"questions"`
+	}
+	if err := decodeJSON(output, &drafts); err != nil {
+		return nil, err
+	}
+	v := validator.New()
+	v.Check(len(drafts.Questions) == len(types), "questions", fmt.Sprintf("must contain exactly %d questions", len(types)))
+	for i, q := range drafts.Questions {
+		key := fmt.Sprintf("questions[%d]", i)
+		if i < len(types) {
+			v.Check(q.Type == types[i], key+".type", fmt.Sprintf("must be %s", types[i]))
+		}
+		v.Check(q.Rubric == nil, key+".rubric", "must not be provided")
+		validateNested(v, key, func(v *validator.Validator) {
+			data.ValidateQuestionDraft(v, &q)
+		})
+	}
+	if !v.Valid() {
+		return nil, validationProblems(v)
+	}
+	return drafts.Questions, nil
+}
+
+func questionDraftTexts(questions []data.BundleQuestion) []string {
+	var texts []string
+	for _, q := range questions {
+		texts = append(texts, q.Question, q.PromptGuidance)
+		for _, o := range q.Options {
+			texts = append(texts, o.OptionText, o.Feedback, o.MatchText)
+		}
+	}
+	return texts
+}
+
+func validateNested(v *validator.Validator, prefix string, validate func(*validator.Validator)) {
+	nested := validator.New()
+	validate(nested)
+	for key, message := range nested.Errors {
+		v.AddError(prefix+"."+key, message)
+	}
+}
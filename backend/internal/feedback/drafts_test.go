@@ -0,0 +1,41 @@
+package feedback
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+)
+
+func TestDraftQuestions(t *testing.T) {
+	info := "Läs inlägget nedan. Det påstås att en ny studie visar att kaffe botar förkylning, men inlägget saknar källa och länk."
+	fake := llm.NewFake()
+	fake.Replies = []string{
+		`{"questions": [{"type": "free_text", "question": "Vem har skrivit inlägget?", "prompt_guidance": "Inlägget saknar avsändare.", "options": []}]}`,
+		`{"questions": [
+			{"type": "true_false", "question": "Inlägget anger en källa.", "prompt_guidance": "Det gör det inte.", "options": [
+				{"option_text": "Sant", "is_correct": false, "feedback": "Titta igen, det finns ingen länk.", "match_text": ""},
+				{"option_text": "Falskt", "is_correct": true, "feedback": "Rätt, källa saknas.", "match_text": ""}
+			]},
+			{"type": "free_text", "question": "Det påstås att en ny studie visar att kaffe botar förkylning, men inlägget saknar källa och länk. Hur tar du reda på mer?", "prompt_guidance": "Leta upp studien.", "options": []}
+		]}`,
+	}
+	g := &Generator{LLM: fake, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	media := []data.PromptMedia{{Type: data.ImageType, Name: "skarmdump.png"}}
+
+	questions, err := g.DraftQuestions(context.Background(), data.DefaultPromptTemplates[data.PromptQuestionDrafts], data.LanguageSwedish, info, media, []data.ExerciseType{data.TrueFalseType, data.FreeTextType})
+	assert.NilError(t, err)
+	assert.Equal(t, len(fake.Prompts), 2)
+	assert.StringContains(t, fake.Prompts[0], "<exercise_info>\n"+info+"\n</exercise_info>\n")
+	assert.StringContains(t, fake.Prompts[0], "- image \"skarmdump.png\"\n")
+	assert.StringContains(t, fake.Prompts[1], "questions must contain exactly 2 questions; questions[0].type must be true_false")
+
+	assert.Equal(t, len(questions), 2)
+	assert.Equal(t, questions[0].Type, data.TrueFalseType)
+	assert.Equal(t, questions[0].Options[1].IsCorrect, true)
+	assert.Equal(t, questions[1].PromptGuidance, "Leta upp studien.")
+}
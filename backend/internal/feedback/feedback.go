@@ -0,0 +1,267 @@
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/promptguard"
+	"github.com/berberapan/info-eval/internal/redact"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+const JSONAttempts = 3
+
+var NoFeedbackMessages = map[data.Language]string{
+	data.LanguageSwedish: "Ingen specifik återkoppling genererades.",
+	data.LanguageEnglish: "No specific feedback generated.",
+}
+
+type Result struct {
+	Text             string
+	Assessment       *data.Assessment
+	PromptTemplateID uuid.NullUUID
+}
+
+// Generator turns one answer into feedback: it renders the template, asks the
+// model and checks what comes back. Questions with a rubric get a structured
+// assessment, everything else gets free text. Personal data in the answer is
+// replaced with placeholders before the model sees it and put back into the
+// feedback afterwards.
+type Generator struct {
+	LLM    llm.Provider
+	Logger *slog.Logger
+	// Redacted is told what was taken out of student text before each model
+	// call. The call isn't made if it returns an error.
+	Redacted func(what string, questionID uuid.UUID, matches []redact.Match) error
+}
+
+func Vars(language data.Language, question data.ExerciseQuestion, studentAnswer string) data.PromptVars {
+	return data.PromptVars{
+		Question: question.Question,
+		Guidance: question.PromptGuidance.String,
+		Answer:   promptguard.Delimit(studentAnswer),
+		Language: language.Name(),
+		Rubric:   question.Rubric,
+	}
+}
+
+func PromptTemplateID(template *data.PromptTemplate) uuid.NullUUID {
+	return uuid.NullUUID{UUID: template.ID, Valid: template.ID != uuid.Nil}
+}
+
+func (g *Generator) Generate(ctx context.Context, template *data.PromptTemplate, language data.Language, question data.ExerciseQuestion, studentAnswer string) (*Result, error) {
+	redactor := redact.New()
+	result, err := g.GenerateRedacted(ctx, template, language, question, redactor, redactor.Redact(studentAnswer))
+	if err != nil {
+		return nil, err
+	}
+	result.Restore(redactor)
+	return result, nil
+}
+
+// GenerateRedacted is Generate for an answer the caller has already passed
+// through redactor, for instance to use it as a cache key. The result keeps
+// the placeholders until Restore is called.
+func (g *Generator) GenerateRedacted(ctx context.Context, template *data.PromptTemplate, language data.Language, question data.ExerciseQuestion, redactor *redact.Redactor, studentAnswer string) (*Result, error) {
+	if question.Rubric != nil {
+		assessment, err := g.assess(ctx, template, language, question, redactor, studentAnswer)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Text: assessment.Feedback, Assessment: assessment, PromptTemplateID: PromptTemplateID(template)}, nil
+	}
+	if err := g.redacted("feedback", question.ID, redactor); err != nil {
+		return nil, err
+	}
+	prompt, err := template.Render(Vars(language, question, studentAnswer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	text, err := g.LLM.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to generate feedback: %w", g.LLM.Name(), err)
+	}
+	if err := promptguard.CheckFeedback(text, prompt); err != nil {
+		return nil, fmt.Errorf("generated feedback was rejected: %w", err)
+	}
+	if text == "" {
+		text = NoFeedbackMessages[language]
+	}
+	return &Result{Text: text, PromptTemplateID: PromptTemplateID(template)}, nil
+}
+
+// Restore puts the personal data redactor took out back into the feedback.
+func (r *Result) Restore(redactor *redact.Redactor) {
+	r.Text = redactor.Restore(r.Text)
+	if r.Assessment != nil {
+		restoreAssessment(r.Assessment, redactor)
+	}
+}
+
+func (g *Generator) Assess(ctx context.Context, template *data.PromptTemplate, language data.Language, question data.ExerciseQuestion, studentAnswer string) (*data.Assessment, error) {
+	redactor := redact.New()
+	assessment, err := g.assess(ctx, template, language, question, redactor, redactor.Redact(studentAnswer))
+	if err != nil {
+		return nil, err
+	}
+	restoreAssessment(assessment, redactor)
+	return assessment, nil
+}
+
+func (g *Generator) assess(ctx context.Context, template *data.PromptTemplate, language data.Language, question data.ExerciseQuestion, redactor *redact.Redactor, studentAnswer string) (*data.Assessment, error) {
+	if err := g.redacted("assessment", question.ID, redactor); err != nil {
+		return nil, err
+	}
+	basePrompt, err := template.Render(Vars(language, question, studentAnswer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	var assessment *data.Assessment
+	err = g.generateJSON(ctx, "assessment", basePrompt, rubricSchema(question.Rubric), func(output string) error {
+		assessment, err = parseAssessment(question.Rubric, output)
+		if err != nil {
+			return err
+		}
+		return checkLeaks(basePrompt, assessmentTexts(assessment)...)
+	}, "question_id", question.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	return assessment, nil
+}
+
+func restoreAssessment(assessment *data.Assessment, redactor *redact.Redactor) {
+	assessment.Feedback = redactor.Restore(assessment.Feedback)
+	for i := range assessment.Criteria {
+		assessment.Criteria[i].Justification = redactor.Restore(assessment.Criteria[i].Justification)
+	}
+}
+
+// redacted logs what kinds of personal data were taken out, never the values,
+// and passes the matches on to the Redacted hook.
+func (g *Generator) redacted(what string, questionID uuid.UUID, redactor *redact.Redactor) error {
+	matches := redactor.Matches()
+	if len(matches) == 0 {
+		return nil
+	}
+	kinds := make([]string, len(matches))
+	for i, m := range matches {
+		kinds[i] = string(m.Kind)
+	}
+	g.Logger.Info("Redacted personal data before calling the model", "what", what, "question_id", questionID.String(), "kinds", kinds)
+	if g.Redacted == nil {
+		return nil
+	}
+	if err := g.Redacted(what, questionID, matches); err != nil {
+		return fmt.Errorf("failed to record redactions: %w", err)
+	}
+	return nil
+}
+
+// generateJSON asks for structured output and hands it to parse. When parse
+// rejects the reply, the model gets another try with the reason appended to
+// the prompt.
+func (g *Generator) generateJSON(ctx context.Context, what, basePrompt string, schema *llm.Schema, parse func(output string) error, logArgs ...any) error {
+	prompt := basePrompt
+	var lastErr error
+	for attempt := 1; attempt <= JSONAttempts; attempt++ {
+		output, err := g.LLM.GenerateJSON(ctx, prompt, schema)
+		if err != nil {
+			return fmt.Errorf("%s failed to generate %s: %w", g.LLM.Name(), what, err)
+		}
+		if err = parse(output); err == nil {
+			return nil
+		}
+		lastErr = err
+		g.Logger.Warn("Generated "+what+" failed validation", append([]any{"attempt", attempt, "max_attempts", JSONAttempts, "error", err}, logArgs...)...)
+		prompt = fmt.Sprintf("%s\n\nYour previous reply was rejected because %s. Reply again with JSON that follows the schema exactly.", basePrompt, err)
+	}
+	return fmt.Errorf("%s failed validation after %d attempts: %w", what, JSONAttempts, lastErr)
+}
+
+func decodeJSON(output string, dst any) error {
+	decoder := json.NewDecoder(strings.NewReader(output))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("the reply is not valid JSON: %w", err)
+	}
+	return nil
+}
+
+func validationProblems(v *validator.Validator) error {
+	problems := make([]string, 0, len(v.Errors))
+	for key, message := range v.Errors {
+		problems = append(problems, fmt.Sprintf("%s %s", key, message))
+	}
+	slices.Sort(problems)
+	return errors.New(strings.Join(problems, "; "))
+}
+
+func parseAssessment(rubric *data.Rubric, output string) (*data.Assessment, error) {
+	var assessment data.Assessment
+	if err := decodeJSON(output, &assessment); err != nil {
+		return nil, err
+	}
+	v := validator.New()
+	if data.ValidateAssessment(v, rubric, &assessment); !v.Valid() {
+		return nil, validationProblems(v)
+	}
+	rubric.Score(&assessment)
+	return &assessment, nil
+}
+
+func assessmentTexts(assessment *data.Assessment) []string {
+	texts := []string{assessment.Feedback}
+	for _, c := range assessment.Criteria {
+		texts = append(texts, c.Justification)
+	}
+	return texts
+}
+
+func checkLeaks(prompt string, texts ...string) error {
+	for _, text := range texts {
+		if err := promptguard.CheckLeak(text, prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rubricSchema(rubric *data.Rubric) *llm.Schema {
+	var criteria, levels []string
+	for _, c := range rubric.Criteria {
+		criteria = append(criteria, c.Name)
+		for _, l := range c.Levels {
+			if !slices.Contains(levels, l.Label) {
+				levels = append(levels, l.Label)
+			}
+		}
+	}
+	return &llm.Schema{
+		Type: llm.TypeObject,
+		Properties: map[string]*llm.Schema{
+			"criteria": {
+				Type: llm.TypeArray,
+				Items: &llm.Schema{
+					Type: llm.TypeObject,
+					Properties: map[string]*llm.Schema{
+						"criterion":     {Type: llm.TypeString, Enum: criteria},
+						"level":         {Type: llm.TypeString, Enum: levels},
+						"justification": {Type: llm.TypeString},
+					},
+					Required: []string{"criterion", "level", "justification"},
+				},
+			},
+			"feedback": {Type: llm.TypeString},
+		},
+		Required: []string{"criteria", "feedback"},
+	}
+}
@@ -0,0 +1,52 @@
+package feedback
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/redact"
+	"github.com/google/uuid"
+)
+
+func TestGenerateRedactsPersonalData(t *testing.T) {
+	fake := llm.NewFake()
+	fake.Replies = []string{"Bra jobbat [NAME_1], du hittade avsändaren."}
+	var recorded []redact.Match
+	g := &Generator{
+		LLM:    fake,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Redacted: func(what string, questionID uuid.UUID, matches []redact.Match) error {
+			recorded = append(recorded, matches...)
+			return nil
+		},
+	}
+	question := data.ExerciseQuestion{ID: uuid.New(), ExerciseType: data.FreeTextType, Question: "Vem står bakom sidan?"}
+	answer := "Jag heter Åsa Lind, maila asa@skola.se. Det är en myndighet."
+
+	result, err := g.Generate(context.Background(), data.DefaultPromptTemplates[data.PromptFreeText], data.LanguageSwedish, question, answer)
+	assert.NilError(t, err)
+	assert.StringContains(t, fake.Prompts[0], "Jag heter [NAME_1], maila [EMAIL_1]. Det är en myndighet.")
+	assert.Equal(t, strings.Contains(fake.Prompts[0], "Åsa"), false)
+	assert.Equal(t, result.Text, "Bra jobbat Åsa Lind, du hittade avsändaren.")
+	assert.Equal(t, len(recorded), 2)
+
+	fake.Replies = []string{"Bra jobbat [NAME_1]."}
+	redactor := redact.New()
+	redacted, err := g.GenerateRedacted(context.Background(), data.DefaultPromptTemplates[data.PromptFreeText], data.LanguageSwedish, question, redactor, redactor.Redact(answer))
+	assert.NilError(t, err)
+	assert.Equal(t, redacted.Text, "Bra jobbat [NAME_1].")
+	redacted.Restore(redactor)
+	assert.Equal(t, redacted.Text, "Bra jobbat Åsa Lind.")
+
+	fake.Replies = []string{"Bra jobbat!"}
+	g.Redacted = func(string, uuid.UUID, []redact.Match) error { return io.ErrClosedPipe }
+	_, err = g.Generate(context.Background(), data.DefaultPromptTemplates[data.PromptFreeText], data.LanguageSwedish, question, answer)
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, len(fake.Prompts), 2)
+}
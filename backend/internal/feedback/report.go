@@ -0,0 +1,185 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/promptguard"
+	"github.com/berberapan/info-eval/internal/redact"
+	"github.com/berberapan/info-eval/internal/validator"
+)
+
+const (
+	MaxReportAnswers     = 200
+	maxReportAnswerChars = 2_000
+	minIdentifyingDigits = 9
+	redactedQuotePart    = "[…]"
+	quoteTrimCharacters  = ` "'“”‘’«»`
+)
+
+var digitGroupsRX = regexp.MustCompile(`\+?\d[\d -]{6,}\d`)
+
+var classReportSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"themes": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"name":        {Type: llm.TypeString},
+					"description": {Type: llm.TypeString},
+					"answers":     {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeInteger}},
+					"examples":    {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+				},
+				Required: []string{"name", "description", "answers", "examples"},
+			},
+		},
+		"misconceptions": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"description": {Type: llm.TypeString},
+					"suggestion":  {Type: llm.TypeString},
+					"answers":     {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeInteger}},
+					"examples":    {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+				},
+				Required: []string{"description", "suggestion", "answers", "examples"},
+			},
+		},
+	},
+	Required: []string{"themes", "misconceptions"},
+}
+
+// ReportAnswers numbers the answers for the class report prompt, cutting very
+// long ones short so one answer can't crowd out the rest.
+func ReportAnswers(answers []string) []data.PromptAnswer {
+	promptAnswers := make([]data.PromptAnswer, len(answers))
+	for i, answer := range answers {
+		if len(answer) > maxReportAnswerChars {
+			answer = strings.ToValidUTF8(answer[:maxReportAnswerChars], "")
+		}
+		promptAnswers[i] = data.PromptAnswer{Number: i + 1, Answer: promptguard.Delimit(answer)}
+	}
+	return promptAnswers
+}
+
+// ClassReport reads every answer to one question together and groups them
+// into themes and misconceptions for the teacher. Answers are numbered in the
+// prompt; the numbers only come back as counts, and example quotes are kept
+// only if they really appear in an answer, with personal data cut out.
+// Nothing is restored here, since the report is meant to be anonymous.
+func (g *Generator) ClassReport(ctx context.Context, template *data.PromptTemplate, language data.Language, question data.ExerciseQuestion, answers []string) (*data.ClassReport, error) {
+	redactor := redact.New()
+	redacted := make([]string, len(answers))
+	for i, answer := range answers {
+		redacted[i] = redactor.Redact(answer)
+	}
+	if err := g.redacted("class report", question.ID, redactor); err != nil {
+		return nil, err
+	}
+	answers = redacted
+	vars := data.PromptVars{Question: question.Question, Guidance: question.PromptGuidance.String, Language: language.Name(), Answers: ReportAnswers(answers)}
+	prompt, err := template.Render(vars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	var report *data.ClassReport
+	err = g.generateJSON(ctx, "class report", prompt, classReportSchema, func(output string) error {
+		report, err = parseClassReport(output, answers)
+		if err != nil {
+			return err
+		}
+		return checkLeaks(prompt, classReportTexts(report)...)
+	}, "question_id", question.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func parseClassReport(output string, answers []string) (*data.ClassReport, error) {
+	var report data.ClassReport
+	if err := decodeJSON(output, &report); err != nil {
+		return nil, err
+	}
+	v := validator.New()
+	if data.ValidateClassReport(v, &report, len(answers)); !v.Valid() {
+		return nil, validationProblems(v)
+	}
+	for i := range report.Themes {
+		t := &report.Themes[i]
+		t.Count, t.Answers = len(t.Answers), nil
+		t.Examples = reportQuotes(t.Examples, answers)
+	}
+	for i := range report.Misconceptions {
+		m := &report.Misconceptions[i]
+		m.Count, m.Answers = len(m.Answers), nil
+		m.Examples = reportQuotes(m.Examples, answers)
+	}
+	if report.Misconceptions == nil {
+		report.Misconceptions = []data.ClassReportMisconception{}
+	}
+	return &report, nil
+}
+
+func classReportTexts(report *data.ClassReport) []string {
+	var texts []string
+	for _, t := range report.Themes {
+		texts = append(texts, t.Name, t.Description)
+		texts = append(texts, t.Examples...)
+	}
+	for _, m := range report.Misconceptions {
+		texts = append(texts, m.Description, m.Suggestion)
+		texts = append(texts, m.Examples...)
+	}
+	return texts
+}
+
+// reportQuotes drops examples the model made up or paraphrased, so a quote
+// in the report is always something a student actually wrote.
+func reportQuotes(examples, answers []string) []string {
+	quotes := []string{}
+	for _, example := range examples {
+		example = strings.Trim(example, quoteTrimCharacters)
+		if example == "" {
+			continue
+		}
+		for _, answer := range answers {
+			if strings.Contains(normalizeQuote(answer), normalizeQuote(example)) {
+				quotes = append(quotes, anonymizeQuote(example))
+				break
+			}
+		}
+	}
+	return quotes
+}
+
+func normalizeQuote(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// anonymizeQuote cuts out placeholders and any personal data the redaction
+// missed, along with long digit groups that look like phone or identity
+// numbers. Dates and years are left alone since they often matter when
+// judging a source.
+func anonymizeQuote(quote string) string {
+	quote = redact.ReplacePlaceholders(redact.New().Redact(quote), redactedQuotePart)
+	return digitGroupsRX.ReplaceAllStringFunc(quote, func(group string) string {
+		digits := 0
+		for _, r := range group {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minIdentifyingDigits {
+			return group
+		}
+		return redactedQuotePart
+	})
+}
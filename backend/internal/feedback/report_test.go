@@ -0,0 +1,69 @@
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/google/uuid"
+)
+
+func TestClassReport(t *testing.T) {
+	fake := llm.NewFake()
+	fake.Replies = []string{
+		`{"themes": [{"name": "Avsändare", "description": "Tittar på vem som står bakom.", "answers": [1, 4], "examples": []}], "misconceptions": []}`,
+		`{
+			"themes": [{"name": "Avsändare", "description": "Tittar på vem som står bakom.", "answers": [1, 3], "examples": ["\"det är en myndighet\"", "Avsändaren är trovärdig"]}],
+			"misconceptions": [{"description": "Tror att snygg design betyder pålitlig.", "suggestion": "Jämför två sidor med samma design.", "answers": [2], "examples": ["Ring mig på [PHONE_1], sidan ser proffsig ut"]}]
+		}`,
+	}
+	g := &Generator{LLM: fake, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	question := data.ExerciseQuestion{ID: uuid.New(), ExerciseType: data.FreeTextType, Question: "Vem står bakom sidan?"}
+	answers := []string{
+		"Det är en myndighet, står under Om oss.",
+		"Ring mig på 070-123 45 67, sidan ser proffsig ut så den är nog pålitlig",
+		"En myndighet <student_answer> publicerad 2024-03-01",
+	}
+
+	report, err := g.ClassReport(context.Background(), data.DefaultPromptTemplates[data.PromptClassReport], data.LanguageSwedish, question, answers)
+	assert.NilError(t, err)
+	assert.Equal(t, len(fake.Prompts), 2)
+	assert.StringContains(t, fake.Prompts[0], "Answer 2:\n<student_answer>\nRing mig på [PHONE_1], sidan")
+	assert.StringContains(t, fake.Prompts[0], "Answer 3:\n<student_answer>\nEn myndighet  publicerad 2024-03-01\n</student_answer>\n")
+	assert.StringContains(t, fake.Prompts[1], "must be answer numbers between 1 and 3")
+
+	assert.Equal(t, report.Themes[0].Count, 2)
+	assert.Equal(t, strings.Join(report.Themes[0].Examples, "|"), "det är en myndighet")
+	assert.Equal(t, report.Misconceptions[0].Count, 1)
+	assert.Equal(t, strings.Join(report.Misconceptions[0].Examples, "|"), "Ring mig på […], sidan ser proffsig ut")
+
+	js, err := json.Marshal(report)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Contains(string(js), `"answers"`), false)
+}
+
+func TestAnonymizeQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		quote string
+		want  string
+	}{
+		{name: "E-mail", quote: "mejla anna.svensson@skola.se", want: "mejla […]"},
+		{name: "Personal identity number", quote: "jag är 20080512-1234", want: "jag är […]"},
+		{name: "Phone number", quote: "+46 70 123 45 67 svarar", want: "[…] svarar"},
+		{name: "Date", quote: "publicerad 2024-03-01", want: "publicerad 2024-03-01"},
+		{name: "Year range", quote: "mellan 1990 och 2020", want: "mellan 1990 och 2020"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, anonymizeQuote(tt.quote), tt.want)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/llm"
+	"github.com/berberapan/info-eval/internal/promptguard"
+	"github.com/berberapan/info-eval/internal/redact"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+var summarySchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"strengths":  {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+		"weaknesses": {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+		"next_step":  {Type: llm.TypeString},
+	},
+	Required: []string{"strengths", "weaknesses", "next_step"},
+}
+
+func SummaryAnswer(question, studentAnswer, feedback string) data.PromptAnswer {
+	return data.PromptAnswer{Question: question, Answer: promptguard.Delimit(studentAnswer), Feedback: feedback}
+}
+
+// Summarize looks across all of a response's answers at once and returns the
+// overall picture: what the student does well, what keeps going wrong and
+// what to practise next.
+func (g *Generator) Summarize(ctx context.Context, template *data.PromptTemplate, language data.Language, answers []data.PromptAnswer) (*data.FeedbackSummary, error) {
+	redactor := redact.New()
+	redacted := make([]data.PromptAnswer, len(answers))
+	for i, a := range answers {
+		redacted[i] = data.PromptAnswer{Number: a.Number, Question: a.Question, Answer: redactor.Redact(a.Answer), Feedback: redactor.Redact(a.Feedback)}
+	}
+	if err := g.redacted("summary", uuid.Nil, redactor); err != nil {
+		return nil, err
+	}
+	prompt, err := template.Render(data.PromptVars{Language: language.Name(), Answers: redacted})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	var summary *data.FeedbackSummary
+	err = g.generateJSON(ctx, "summary", prompt, summarySchema, func(output string) error {
+		summary, err = parseSummary(output)
+		if err != nil {
+			return err
+		}
+		return checkLeaks(prompt, slices.Concat(summary.Strengths, summary.Weaknesses, []string{summary.NextStep})...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, items := range [][]string{summary.Strengths, summary.Weaknesses} {
+		for i := range items {
+			items[i] = redactor.Restore(items[i])
+		}
+	}
+	summary.NextStep = redactor.Restore(summary.NextStep)
+	return summary, nil
+}
+
+func parseSummary(output string) (*data.FeedbackSummary, error) {
+	var summary data.FeedbackSummary
+	if err := decodeJSON(output, &summary); err != nil {
+		return nil, err
+	}
+	v := validator.New()
+	if data.ValidateFeedbackSummary(v, &summary); !v.Valid() {
+		return nil, validationProblems(v)
+	}
+	return &summary, nil
+}
@@ -0,0 +1,333 @@
+package grading
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+type Result struct {
+	Answered bool     `json:"answered"`
+	Correct  bool     `json:"correct"`
+	Score    float64  `json:"score"`
+	MaxScore float64  `json:"max_score"`
+	Feedback []string `json:"feedback,omitempty"`
+}
+
+type Summary struct {
+	Questions map[string]Result `json:"questions"`
+	Score     float64           `json:"score"`
+	MaxScore  float64           `json:"max_score"`
+}
+
+func optionIDs(question *data.ExerciseQuestion) []string {
+	ids := make([]string, len(question.Options))
+	for i, o := range question.Options {
+		ids[i] = o.ID.String()
+	}
+	return ids
+}
+
+func optionIndex(question *data.ExerciseQuestion, id string) int {
+	return slices.Index(optionIDs(question), id)
+}
+
+func stringSlice(answer any) ([]string, bool) {
+	items, ok := answer.([]any)
+	if !ok {
+		return nil, false
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+	return values, true
+}
+
+func stringMap(answer any) (map[string]string, bool) {
+	items, ok := answer.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	values := make(map[string]string, len(items))
+	for key, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		values[key] = value
+	}
+	return values, true
+}
+
+func ValidateAnswer(v *validator.Validator, key string, question *data.ExerciseQuestion, answer any) {
+	switch question.ExerciseType {
+	case data.FreeTextType:
+		text, ok := answer.(string)
+		v.Check(ok, key, "must be a string")
+		v.Check(len(text) <= 10_000, key, "can't exceed 10000 chars")
+
+	case data.TrueFalseType, data.MultipleChoiceType, data.LikertType:
+		id, ok := answer.(string)
+		if !ok {
+			v.AddError(key, "must be an option id")
+			return
+		}
+		v.Check(optionIndex(question, id) >= 0, key, "must be one of the question's options")
+
+	case data.MultiSelectType:
+		ids, ok := stringSlice(answer)
+		if !ok {
+			v.AddError(key, "must be a list of option ids")
+			return
+		}
+		v.Check(validator.Unique(ids), key, "must not contain duplicate options")
+		for _, id := range ids {
+			v.Check(optionIndex(question, id) >= 0, key, "must only contain the question's options")
+		}
+
+	case data.OrderingType:
+		ids, ok := stringSlice(answer)
+		if !ok {
+			v.AddError(key, "must be a list of option ids")
+			return
+		}
+		v.Check(validator.Unique(ids), key, "must not contain duplicate options")
+		v.Check(len(ids) == len(question.Options), key, fmt.Sprintf("must rank all %d options", len(question.Options)))
+		for _, id := range ids {
+			v.Check(optionIndex(question, id) >= 0, key, "must only contain the question's options")
+		}
+
+	case data.MatchingType:
+		matches, ok := stringMap(answer)
+		if !ok {
+			v.AddError(key, "must be an object mapping option ids to match texts")
+			return
+		}
+		targets := make([]string, len(question.Options))
+		for i, o := range question.Options {
+			targets[i] = o.MatchText
+		}
+		for id, target := range matches {
+			v.Check(optionIndex(question, id) >= 0, key, "must only contain the question's options")
+			v.Check(slices.Contains(targets, target), key, "must only match against the question's match texts")
+		}
+
+	default:
+		v.AddError(key, fmt.Sprintf("can't answer %s questions", question.ExerciseType))
+	}
+}
+
+func Gradable(question *data.ExerciseQuestion) bool {
+	if !question.ExerciseType.HasOptions() || len(question.Options) == 0 {
+		return false
+	}
+	switch question.ExerciseType {
+	case data.TrueFalseType, data.MultipleChoiceType, data.LikertType:
+		return slices.ContainsFunc(question.Options, func(o data.QuestionOption) bool { return o.IsCorrect })
+	default:
+		return true
+	}
+}
+
+func Grade(question *data.ExerciseQuestion, answer any) (Result, bool) {
+	if !Gradable(question) {
+		return Result{}, false
+	}
+	var result Result
+	var ok bool
+	switch question.ExerciseType {
+	case data.TrueFalseType, data.MultipleChoiceType:
+		result, ok = gradeSingleChoice(question, answer)
+	case data.MultiSelectType:
+		result, ok = gradeMultiSelect(question, answer)
+	case data.OrderingType:
+		result, ok = gradeOrdering(question, answer)
+	case data.MatchingType:
+		result, ok = gradeMatching(question, answer)
+	case data.LikertType:
+		result, ok = gradeLikert(question, answer)
+	}
+	if !ok {
+		return Result{}, false
+	}
+	result.Answered = true
+	result.Feedback = selectedFeedback(question, answer)
+	return result, true
+}
+
+func GradeAll(questions map[uuid.UUID]data.ExerciseQuestion, answers map[string]any) Summary {
+	summary := Summary{Questions: make(map[string]Result)}
+	for id, question := range questions {
+		if !Gradable(&question) {
+			continue
+		}
+		result := Result{MaxScore: 1}
+		if answer, ok := answers[id.String()]; ok {
+			if graded, ok := Grade(&question, answer); ok {
+				result = graded
+			}
+		}
+		summary.Questions[id.String()] = result
+		summary.Score += result.Score
+		summary.MaxScore += result.MaxScore
+	}
+	return summary
+}
+
+// Describe writes an answer the way a reader would see it, with option texts
+// instead of option ids.
+func Describe(question *data.ExerciseQuestion, answer any) string {
+	optionText := func(id string) string {
+		if i := optionIndex(question, id); i >= 0 {
+			return question.Options[i].OptionText
+		}
+		return id
+	}
+	switch answer := answer.(type) {
+	case string:
+		if question.ExerciseType.HasOptions() {
+			return optionText(answer)
+		}
+		return answer
+	case []any:
+		ids, _ := stringSlice(answer)
+		texts := make([]string, len(ids))
+		for i, id := range ids {
+			texts[i] = optionText(id)
+		}
+		if question.ExerciseType == data.OrderingType {
+			return strings.Join(texts, " > ")
+		}
+		return strings.Join(texts, ", ")
+	case map[string]any:
+		matches, _ := stringMap(answer)
+		pairs := make([]string, 0, len(matches))
+		for _, o := range question.Options {
+			if target, ok := matches[o.ID.String()]; ok {
+				pairs = append(pairs, fmt.Sprintf("%s: %s", o.OptionText, target))
+			}
+		}
+		return strings.Join(pairs, "; ")
+	default:
+		return ""
+	}
+}
+
+func selectedFeedback(question *data.ExerciseQuestion, answer any) []string {
+	var selected []string
+	switch answer := answer.(type) {
+	case string:
+		selected = []string{answer}
+	case []any:
+		if question.ExerciseType == data.MultiSelectType {
+			selected, _ = stringSlice(answer)
+		}
+	}
+	var feedback []string
+	for _, o := range question.Options {
+		if o.Feedback != "" && slices.Contains(selected, o.ID.String()) {
+			feedback = append(feedback, o.Feedback)
+		}
+	}
+	return feedback
+}
+
+func gradeSingleChoice(question *data.ExerciseQuestion, answer any) (Result, bool) {
+	id, ok := answer.(string)
+	if !ok {
+		return Result{}, false
+	}
+	index := optionIndex(question, id)
+	if index < 0 {
+		return Result{}, false
+	}
+	if question.Options[index].IsCorrect {
+		return Result{Correct: true, Score: 1, MaxScore: 1}, true
+	}
+	return Result{MaxScore: 1}, true
+}
+
+func fraction(correct, total int) Result {
+	return Result{
+		Correct:  correct == total,
+		Score:    float64(correct) / float64(total),
+		MaxScore: 1,
+	}
+}
+
+func gradeMultiSelect(question *data.ExerciseQuestion, answer any) (Result, bool) {
+	selected, ok := stringSlice(answer)
+	if !ok {
+		return Result{}, false
+	}
+	correct := 0
+	for _, o := range question.Options {
+		if slices.Contains(selected, o.ID.String()) == o.IsCorrect {
+			correct++
+		}
+	}
+	return fraction(correct, len(question.Options)), true
+}
+
+func gradeOrdering(question *data.ExerciseQuestion, answer any) (Result, bool) {
+	ranked, ok := stringSlice(answer)
+	if !ok {
+		return Result{}, false
+	}
+	correct := 0
+	for i, o := range question.Options {
+		if i < len(ranked) && ranked[i] == o.ID.String() {
+			correct++
+		}
+	}
+	return fraction(correct, len(question.Options)), true
+}
+
+func gradeMatching(question *data.ExerciseQuestion, answer any) (Result, bool) {
+	matches, ok := stringMap(answer)
+	if !ok {
+		return Result{}, false
+	}
+	correct := 0
+	for _, o := range question.Options {
+		if target, ok := matches[o.ID.String()]; ok && target == o.MatchText {
+			correct++
+		}
+	}
+	return fraction(correct, len(question.Options)), true
+}
+
+// Likert options are the points of the scale in order, and IsCorrect marks the
+// acceptable ratings. Ratings outside them lose score with distance.
+func gradeLikert(question *data.ExerciseQuestion, answer any) (Result, bool) {
+	id, ok := answer.(string)
+	if !ok {
+		return Result{}, false
+	}
+	rating := optionIndex(question, id)
+	if rating < 0 {
+		return Result{}, false
+	}
+	distance := len(question.Options)
+	for i, o := range question.Options {
+		if o.IsCorrect {
+			distance = min(distance, max(i-rating, rating-i))
+		}
+	}
+	if distance == 0 {
+		return Result{Correct: true, Score: 1, MaxScore: 1}, true
+	}
+	return Result{
+		Score:    1 - float64(distance)/float64(len(question.Options)-1),
+		MaxScore: 1,
+	}, true
+}
@@ -0,0 +1,164 @@
+package grading
+
+import (
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+	"github.com/berberapan/info-eval/internal/data"
+	"github.com/berberapan/info-eval/internal/validator"
+	"github.com/google/uuid"
+)
+
+func newQuestion(t data.ExerciseType, options ...data.QuestionOption) *data.ExerciseQuestion {
+	for i := range options {
+		options[i].ID = uuid.New()
+	}
+	return &data.ExerciseQuestion{ID: uuid.New(), ExerciseType: t, Question: "Q", Options: options}
+}
+
+func ids(q *data.ExerciseQuestion, indexes ...int) []any {
+	values := make([]any, len(indexes))
+	for i, index := range indexes {
+		values[i] = q.Options[index].ID.String()
+	}
+	return values
+}
+
+func TestValidateAnswer(t *testing.T) {
+	multi := newQuestion(data.MultiSelectType, data.QuestionOption{IsCorrect: true}, data.QuestionOption{}, data.QuestionOption{IsCorrect: true})
+	ordering := newQuestion(data.OrderingType, data.QuestionOption{}, data.QuestionOption{}, data.QuestionOption{})
+	matching := newQuestion(data.MatchingType, data.QuestionOption{MatchText: "SVT"}, data.QuestionOption{MatchText: "Blogg"})
+	likert := newQuestion(data.LikertType, data.QuestionOption{}, data.QuestionOption{IsCorrect: true}, data.QuestionOption{})
+
+	tests := []struct {
+		name     string
+		question *data.ExerciseQuestion
+		answer   any
+		valid    bool
+	}{
+		{"Free text", newQuestion(data.FreeTextType), "svar", true},
+		{"Free text not a string", newQuestion(data.FreeTextType), 42.0, false},
+		{"Multi-select", multi, ids(multi, 0, 2), true},
+		{"Multi-select empty", multi, []any{}, true},
+		{"Multi-select duplicate", multi, ids(multi, 0, 0), false},
+		{"Multi-select unknown option", multi, []any{uuid.NewString()}, false},
+		{"Multi-select single id", multi, multi.Options[0].ID.String(), false},
+		{"Ordering", ordering, ids(ordering, 2, 0, 1), true},
+		{"Ordering missing option", ordering, ids(ordering, 2, 0), false},
+		{"Matching", matching, map[string]any{matching.Options[0].ID.String(): "Blogg"}, true},
+		{"Matching unknown target", matching, map[string]any{matching.Options[0].ID.String(): "DN"}, false},
+		{"Matching unknown option", matching, map[string]any{uuid.NewString(): "SVT"}, false},
+		{"Likert", likert, likert.Options[2].ID.String(), true},
+		{"Likert list", likert, ids(likert, 1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateAnswer(v, "answer", tt.question, tt.answer)
+			assert.Equal(t, v.Valid(), tt.valid)
+		})
+	}
+}
+
+func TestGrade(t *testing.T) {
+	multi := newQuestion(data.MultiSelectType, data.QuestionOption{IsCorrect: true}, data.QuestionOption{}, data.QuestionOption{IsCorrect: true}, data.QuestionOption{})
+	ordering := newQuestion(data.OrderingType, data.QuestionOption{}, data.QuestionOption{}, data.QuestionOption{}, data.QuestionOption{})
+	matching := newQuestion(data.MatchingType, data.QuestionOption{MatchText: "SVT"}, data.QuestionOption{MatchText: "Blogg"})
+	likert := newQuestion(data.LikertType, data.QuestionOption{}, data.QuestionOption{}, data.QuestionOption{}, data.QuestionOption{}, data.QuestionOption{IsCorrect: true})
+
+	choice := newQuestion(data.MultipleChoiceType, data.QuestionOption{Feedback: "Nej"}, data.QuestionOption{IsCorrect: true, Feedback: "Rätt"})
+	trueFalse := newQuestion(data.TrueFalseType, data.QuestionOption{IsCorrect: true}, data.QuestionOption{})
+
+	tests := []struct {
+		name      string
+		question  *data.ExerciseQuestion
+		answer    any
+		gradable  bool
+		wantScore float64
+		correct   bool
+	}{
+		{"Multiple choice correct", choice, choice.Options[1].ID.String(), true, 1, true},
+		{"Multiple choice wrong", choice, choice.Options[0].ID.String(), true, 0, false},
+		{"True/false correct", trueFalse, trueFalse.Options[0].ID.String(), true, 1, true},
+		{"Multiple choice without correct option", newQuestion(data.MultipleChoiceType, data.QuestionOption{}), nil, false, 0, false},
+		{"Multi-select all correct", multi, ids(multi, 0, 2), true, 1, true},
+		{"Multi-select one missed", multi, ids(multi, 0), true, 0.75, false},
+		{"Multi-select one extra", multi, ids(multi, 0, 1, 2), true, 0.75, false},
+		{"Ordering correct", ordering, ids(ordering, 0, 1, 2, 3), true, 1, true},
+		{"Ordering swapped pair", ordering, ids(ordering, 1, 0, 2, 3), true, 0.5, false},
+		{"Matching correct", matching, map[string]any{matching.Options[0].ID.String(): "SVT", matching.Options[1].ID.String(): "Blogg"}, true, 1, true},
+		{"Matching partial", matching, map[string]any{matching.Options[0].ID.String(): "SVT"}, true, 0.5, false},
+		{"Likert acceptable", likert, likert.Options[4].ID.String(), true, 1, true},
+		{"Likert near miss", likert, likert.Options[3].ID.String(), true, 0.75, false},
+		{"Likert opposite end", likert, likert.Options[0].ID.String(), true, 0, false},
+		{"Free text", newQuestion(data.FreeTextType), "svar", false, 0, false},
+		{"Likert without acceptable ratings", newQuestion(data.LikertType, data.QuestionOption{}, data.QuestionOption{}), nil, false, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer := tt.answer
+			if answer == nil && len(tt.question.Options) > 0 {
+				answer = tt.question.Options[0].ID.String()
+			}
+			result, ok := Grade(tt.question, answer)
+			assert.Equal(t, ok, tt.gradable)
+			assert.Equal(t, result.Score, tt.wantScore)
+			assert.Equal(t, result.Correct, tt.correct)
+		})
+	}
+}
+
+func TestGradeFeedback(t *testing.T) {
+	choice := newQuestion(data.MultipleChoiceType, data.QuestionOption{Feedback: "Nej"}, data.QuestionOption{IsCorrect: true, Feedback: "Rätt"})
+	multi := newQuestion(data.MultiSelectType, data.QuestionOption{IsCorrect: true, Feedback: "A"}, data.QuestionOption{}, data.QuestionOption{Feedback: "C"})
+
+	result, _ := Grade(choice, choice.Options[0].ID.String())
+	assert.Equal(t, len(result.Feedback), 1)
+	assert.Equal(t, result.Feedback[0], "Nej")
+
+	result, _ = Grade(multi, ids(multi, 0, 1, 2))
+	assert.Equal(t, len(result.Feedback), 2)
+	assert.Equal(t, result.Feedback[0], "A")
+	assert.Equal(t, result.Feedback[1], "C")
+}
+
+func TestGradeAll(t *testing.T) {
+	choice := newQuestion(data.MultipleChoiceType, data.QuestionOption{}, data.QuestionOption{IsCorrect: true})
+	ordering := newQuestion(data.OrderingType, data.QuestionOption{}, data.QuestionOption{})
+	skipped := newQuestion(data.TrueFalseType, data.QuestionOption{IsCorrect: true}, data.QuestionOption{})
+	freeText := newQuestion(data.FreeTextType)
+	questions := map[uuid.UUID]data.ExerciseQuestion{
+		choice.ID:   *choice,
+		ordering.ID: *ordering,
+		skipped.ID:  *skipped,
+		freeText.ID: *freeText,
+	}
+	answers := map[string]any{
+		choice.ID.String():   choice.Options[1].ID.String(),
+		ordering.ID.String(): ids(ordering, 1, 0),
+		freeText.ID.String(): "svar",
+	}
+
+	summary := GradeAll(questions, answers)
+
+	assert.Equal(t, len(summary.Questions), 3)
+	assert.Equal(t, summary.Score, 1.0)
+	assert.Equal(t, summary.MaxScore, 3.0)
+	assert.Equal(t, summary.Questions[choice.ID.String()].Correct, true)
+	assert.Equal(t, summary.Questions[skipped.ID.String()].Answered, false)
+	_, ok := summary.Questions[freeText.ID.String()]
+	assert.Equal(t, ok, false)
+}
+
+func TestDescribe(t *testing.T) {
+	choice := newQuestion(data.MultipleChoiceType, data.QuestionOption{OptionText: "Myndighet"}, data.QuestionOption{OptionText: "Företag"})
+	ordering := newQuestion(data.OrderingType, data.QuestionOption{OptionText: "A"}, data.QuestionOption{OptionText: "B"})
+	matching := newQuestion(data.MatchingType, data.QuestionOption{OptionText: "Nyhet", MatchText: "SVT"}, data.QuestionOption{OptionText: "Åsikt", MatchText: "Blogg"})
+
+	assert.Equal(t, Describe(newQuestion(data.FreeTextType), "Ett svar"), "Ett svar")
+	assert.Equal(t, Describe(choice, choice.Options[1].ID.String()), "Företag")
+	assert.Equal(t, Describe(ordering, ids(ordering, 1, 0)), "B > A")
+	assert.Equal(t, Describe(matching, map[string]any{matching.Options[1].ID.String(): "SVT", matching.Options[0].ID.String(): "Blogg"}), "Nyhet: Blogg; Åsikt: SVT")
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sync"
+)
+
+type Fake struct {
+	mu      sync.Mutex
+	Err     error
+	Replies []string
+	Prompts []string
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Generate(ctx context.Context, prompt string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Prompts = append(f.Prompts, prompt)
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if len(f.Replies) > 0 {
+		reply := f.Replies[0]
+		f.Replies = f.Replies[1:]
+		return reply, nil
+	}
+	return fmt.Sprintf("fake feedback %08x", crc32.ChecksumIEEE([]byte(prompt))), nil
+}
+
+func (f *Fake) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	return f.Generate(ctx, prompt)
+}
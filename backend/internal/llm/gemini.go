@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+const DefaultGeminiModel = "gemini-1.5-flash-latest"
+
+type Gemini struct {
+	key   string
+	model string
+}
+
+func NewGemini(key, model string) *Gemini {
+	return &Gemini{key: key, model: model}
+}
+
+func (g *Gemini) Name() string {
+	return "gemini/" + g.model
+}
+
+func (g *Gemini) Generate(ctx context.Context, prompt string) (string, error) {
+	return g.generate(ctx, prompt, nil)
+}
+
+func (g *Gemini) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	return g.generate(ctx, prompt, schema)
+}
+
+func (g *Gemini) generate(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(g.key))
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	model := client.GenerativeModel(g.model)
+	model.SetTemperature(0.1)
+	if schema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = schema.genai()
+	}
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return "", err
+	}
+	return extractText(resp), nil
+}
+
+func extractText(resp *genai.GenerateContentResponse) string {
+	var text strings.Builder
+	if resp != nil {
+		for _, cand := range resp.Candidates {
+			if cand.Content != nil {
+				for _, part := range cand.Content.Parts {
+					if textPart, ok := part.(genai.Text); ok {
+						text.WriteString(string(textPart))
+					}
+				}
+			}
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type Limits struct {
+	Concurrency       int
+	RequestsPerMinute int
+	Timeout           time.Duration
+}
+
+// Limited wraps a provider so every caller shares one cap on in-flight
+// requests and one requests-per-minute budget. The timeout only starts once a
+// request is allowed through, so time spent queueing doesn't count against it.
+type Limited struct {
+	provider Provider
+	slots    chan struct{}
+	limiter  *rate.Limiter
+	timeout  time.Duration
+}
+
+func NewLimited(provider Provider, limits Limits) *Limited {
+	concurrency := max(limits.Concurrency, 1)
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if limits.RequestsPerMinute > 0 {
+		limiter = rate.NewLimiter(rate.Limit(float64(limits.RequestsPerMinute)/60), concurrency)
+	}
+	return &Limited{
+		provider: provider,
+		slots:    make(chan struct{}, concurrency),
+		limiter:  limiter,
+		timeout:  limits.Timeout,
+	}
+}
+
+func (l *Limited) Name() string {
+	return l.provider.Name()
+}
+
+func (l *Limited) do(ctx context.Context, call func(ctx context.Context) (string, error)) (string, error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-l.slots }()
+	if err := l.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+	return call(ctx)
+}
+
+func (l *Limited) Generate(ctx context.Context, prompt string) (string, error) {
+	return l.do(ctx, func(ctx context.Context) (string, error) {
+		return l.provider.Generate(ctx, prompt)
+	})
+}
+
+func (l *Limited) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	return l.do(ctx, func(ctx context.Context) (string, error) {
+		return l.provider.GenerateJSON(ctx, prompt, schema)
+	})
+}
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/berberapan/info-eval/internal/assert"
+)
+
+type slowProvider struct {
+	inFlight atomic.Int32
+	peak     atomic.Int32
+	delay    time.Duration
+}
+
+func (p *slowProvider) Name() string {
+	return "slow"
+}
+
+func (p *slowProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(p.delay):
+		return prompt, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (p *slowProvider) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	return p.Generate(ctx, prompt)
+}
+
+func TestLimitedConcurrency(t *testing.T) {
+	provider := &slowProvider{delay: 20 * time.Millisecond}
+	limited := NewLimited(provider, Limits{Concurrency: 2})
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := limited.Generate(context.Background(), "hej")
+			assert.NilError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, provider.peak.Load(), int32(2))
+	assert.Equal(t, limited.Name(), "slow")
+}
+
+func TestLimitedRequestsPerMinute(t *testing.T) {
+	limited := NewLimited(NewFake(), Limits{Concurrency: 1, RequestsPerMinute: 1})
+
+	_, err := limited.Generate(context.Background(), "first")
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = limited.Generate(ctx, "second")
+	assert.Equal(t, err != nil, true)
+}
+
+func TestLimitedTimeoutExcludesQueueing(t *testing.T) {
+	provider := &slowProvider{delay: 30 * time.Millisecond}
+	limited := NewLimited(provider, Limits{Concurrency: 1, Timeout: 50 * time.Millisecond})
+
+	errs := make(chan error, 3)
+	for range 3 {
+		go func() {
+			_, err := limited.GenerateJSON(context.Background(), "hej", &Schema{Type: TypeString})
+			errs <- err
+		}()
+	}
+	for range 3 {
+		assert.NilError(t, <-errs)
+	}
+
+	provider.delay = 100 * time.Millisecond
+	_, err := limited.Generate(context.Background(), "hej")
+	assert.Equal(t, errors.Is(err, context.DeadlineExceeded), true)
+}
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoAPIKey        = errors.New("no API key configured")
+	ErrEmptyCompletion = errors.New("provider returned an empty completion")
+)
+
+type Provider interface {
+	Name() string
+	Generate(ctx context.Context, prompt string) (string, error)
+	GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error)
+}
+
+type Config struct {
+	Provider string
+	Model    string
+	BaseURL  string
+	APIKey   string
+}
+
+func New(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "gemini":
+		if cfg.APIKey == "" {
+			return nil, ErrNoAPIKey
+		}
+		model := cfg.Model
+		if model == "" {
+			model = DefaultGeminiModel
+		}
+		return NewGemini(cfg.APIKey, model), nil
+	case "openai":
+		if cfg.Model == "" {
+			return nil, errors.New("openai provider requires a model")
+		}
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = DefaultOpenAIBaseURL
+		}
+		return NewOpenAI(baseURL, cfg.APIKey, cfg.Model), nil
+	case "fake":
+		return NewFake(), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DefaultOpenAIBaseURL = "http://localhost:11434/v1"
+
+type OpenAI struct {
+	baseURL string
+	key     string
+	model   string
+	client  *http.Client
+}
+
+func NewOpenAI(baseURL, key, model string) *OpenAI {
+	return &OpenAI{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		key:     key,
+		model:   model,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (o *OpenAI) Name() string {
+	return "openai/" + o.model
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type responseFormat struct {
+	Type       string         `json:"type"`
+	JSONSchema map[string]any `json:"json_schema,omitempty"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Temperature    float32         `json:"temperature"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (o *OpenAI) Generate(ctx context.Context, prompt string) (string, error) {
+	return o.generate(ctx, prompt, nil)
+}
+
+func (o *OpenAI) GenerateJSON(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	return o.generate(ctx, prompt, &responseFormat{
+		Type: "json_schema",
+		JSONSchema: map[string]any{
+			"name":   "response",
+			"strict": true,
+			"schema": schema.jsonSchema(),
+		},
+	})
+}
+
+func (o *OpenAI) generate(ctx context.Context, prompt string, format *responseFormat) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:          o.model,
+		Messages:       []chatMessage{{Role: "user", Content: prompt}},
+		Temperature:    0.1,
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.key != "" {
+		req.Header.Set("Authorization", "Bearer "+o.key)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%s returned %s: %s", o.Name(), resp.Status, bytes.TrimSpace(message))
+	}
+	var completion chatResponse
+	err = json.NewDecoder(resp.Body).Decode(&completion)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s response: %w", o.Name(), err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", ErrEmptyCompletion
+	}
+	return completion.Choices[0].Message.Content, nil
+}
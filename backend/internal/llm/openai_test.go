@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+)
+
+func TestOpenAIGenerate(t *testing.T) {
+	var got chatRequest
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Bra svar"}}]}`))
+	}))
+	defer ts.Close()
+
+	provider := NewOpenAI(ts.URL+"/v1/", "secret", "llama3.1")
+	text, err := provider.Generate(context.Background(), "Hej")
+
+	assert.NilError(t, err)
+	assert.Equal(t, text, "Bra svar")
+	assert.Equal(t, auth, "Bearer secret")
+	assert.Equal(t, got.Model, "llama3.1")
+	assert.Equal(t, got.Messages[0].Content, "Hej")
+}
+
+func TestOpenAIGenerateJSON(t *testing.T) {
+	var got map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"ok\":\"ja\"}"}}]}`))
+	}))
+	defer ts.Close()
+
+	schema := &Schema{Type: TypeObject, Properties: map[string]*Schema{"ok": {Type: TypeString}}, Required: []string{"ok"}}
+	text, err := NewOpenAI(ts.URL, "", "m").GenerateJSON(context.Background(), "Hej", schema)
+
+	assert.NilError(t, err)
+	assert.Equal(t, text, `{"ok":"ja"}`)
+	format := got["response_format"].(map[string]any)
+	assert.Equal(t, format["type"], any("json_schema"))
+	js := format["json_schema"].(map[string]any)["schema"].(map[string]any)
+	assert.Equal(t, js["additionalProperties"], any(false))
+}
+
+func TestOpenAIGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"Server error", http.StatusInternalServerError, `model not loaded`},
+		{"No choices", http.StatusOK, `{"choices":[]}`},
+		{"Malformed body", http.StatusOK, `{`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			_, err := NewOpenAI(ts.URL, "", "m").Generate(context.Background(), "Hej")
+			assert.Equal(t, err != nil, true)
+		})
+	}
+}
+
+func TestFakeIsDeterministic(t *testing.T) {
+	fake := NewFake()
+	a, _ := fake.Generate(context.Background(), "prompt")
+	b, _ := fake.Generate(context.Background(), "prompt")
+
+	assert.Equal(t, a, b)
+	assert.Equal(t, len(fake.Prompts), 2)
+}
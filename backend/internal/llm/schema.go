@@ -0,0 +1,80 @@
+package llm
+
+import "github.com/google/generative-ai-go/genai"
+
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+type Schema struct {
+	Type        string
+	Description string
+	Enum        []string
+	Items       *Schema
+	Properties  map[string]*Schema
+	Required    []string
+}
+
+func (s *Schema) jsonSchema() map[string]any {
+	if s == nil {
+		return nil
+	}
+	js := map[string]any{"type": s.Type}
+	if s.Description != "" {
+		js["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		js["enum"] = s.Enum
+	}
+	if s.Items != nil {
+		js["items"] = s.Items.jsonSchema()
+	}
+	if s.Type == TypeObject {
+		properties := make(map[string]any, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = property.jsonSchema()
+		}
+		js["properties"] = properties
+		js["required"] = s.Required
+		js["additionalProperties"] = false
+	}
+	return js
+}
+
+func (s *Schema) genai() *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	gs := &genai.Schema{
+		Description: s.Description,
+		Enum:        s.Enum,
+		Items:       s.Items.genai(),
+		Required:    s.Required,
+	}
+	switch s.Type {
+	case TypeObject:
+		gs.Type = genai.TypeObject
+	case TypeArray:
+		gs.Type = genai.TypeArray
+	case TypeInteger:
+		gs.Type = genai.TypeInteger
+	case TypeBoolean:
+		gs.Type = genai.TypeBoolean
+	default:
+		gs.Type = genai.TypeString
+	}
+	if len(s.Enum) > 0 {
+		gs.Format = "enum"
+	}
+	if len(s.Properties) > 0 {
+		gs.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			gs.Properties[name] = property.genai()
+		}
+	}
+	return gs
+}
@@ -0,0 +1,118 @@
+package promptguard
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const AnswerTag = "student_answer"
+
+var (
+	ErrLeak   = errors.New("the reply repeats the instructions")
+	ErrFormat = errors.New("the reply doesn't follow the requested format")
+)
+
+var tagRX = regexp.MustCompile(`(?i)<\s*/?\s*` + AnswerTag + `[^>]*>`)
+
+// Delimit wraps untrusted text in answer tags. Tags already present in the
+// text are removed so an answer can't close the block early.
+func Delimit(answer string) string {
+	return fmt.Sprintf("<%s>\n%s\n</%s>", AnswerTag, tagRX.ReplaceAllString(answer, ""), AnswerTag)
+}
+
+type heuristic struct {
+	name string
+	rx   *regexp.Regexp
+}
+
+var heuristics = []heuristic{
+	{"override_instructions", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,40}\b(previous|above|prior|earlier|all|your|the)\b.{0,20}\b(instructions?|prompts?|rules)\b`)},
+	{"override_instructions", regexp.MustCompile(`(?i)\b(ignorera|strunta i|glöm|bortse från)\b.{0,40}\b(tidigare|ovanstående|föregående|alla|dina)\b.{0,20}\b(instruktioner(na)?|regler(na)?)\b`)},
+	{"role_play", regexp.MustCompile(`(?i)\b(you are now|from now on,? you|pretend (to be|you are)|you (should |must |will )?act as|du är nu|låtsas att du|du ska agera som)\b|(^|[.!?]\s*)(please |now )?act as\b`)},
+	{"prompt_probe", regexp.MustCompile(`(?i)\b(system ?prompt|your instructions|dina instruktioner)\b|\b(reveal|show|print|repeat|visa|upprepa)\b.{0,20}\b(your|the|dina)\s+(instructions|prompt|rules|instruktioner|regler)\b`)},
+	{"role_marker", regexp.MustCompile(`(?im)^\s*(system|assistant|user)\s*:|<\|im_(start|end)\|>|\[/?INST\]`)},
+	{"grade_request", regexp.MustCompile(`(?i)\b(give|award|rate)\b.{0,30}\b(full (marks|score|points)|perfect|maximum|max)\b|\b(ge|sätt)\b.{0,30}\b(full poäng|högsta betyg|maxpoäng|alla poäng)\b|\b(say|write|state|confirm|mark)\b.{0,15}\b(this|the|my) answer (is|as) (perfect|correct|excellent)\b`)},
+	{"delimiter", tagRX},
+}
+
+// Inspect returns the names of the heuristics the answer trips, so it can be
+// held for a teacher to look at.
+func Inspect(answer string) []string {
+	var flags []string
+	for _, h := range heuristics {
+		if h.rx.MatchString(answer) && !slices.Contains(flags, h.name) {
+			flags = append(flags, h.name)
+		}
+	}
+	return flags
+}
+
+const leakWindow = 12
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// CheckLeak rejects text that echoes the delimiter tags or any run of
+// leakWindow words from the prompt outside the student's answer.
+func CheckLeak(text, prompt string) error {
+	if strings.Contains(strings.ToLower(text), AnswerTag) {
+		return ErrLeak
+	}
+	instructions := words(stripAnswer(prompt))
+	if len(instructions) < leakWindow {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for i := 0; i+leakWindow <= len(instructions); i++ {
+		seen[strings.Join(instructions[i:i+leakWindow], " ")] = true
+	}
+	reply := words(text)
+	for i := 0; i+leakWindow <= len(reply); i++ {
+		if seen[strings.Join(reply[i:i+leakWindow], " ")] {
+			return ErrLeak
+		}
+	}
+	return nil
+}
+
+func stripAnswer(prompt string) string {
+	start := strings.Index(prompt, "<"+AnswerTag+">")
+	end := strings.LastIndex(prompt, "</"+AnswerTag+">")
+	if start < 0 || end < start {
+		return prompt
+	}
+	return prompt[:start] + prompt[end+len(AnswerTag)+3:]
+}
+
+const (
+	maxFeedbackChars     = 1_500
+	maxFeedbackSentences = 6
+)
+
+var sentenceEndRX = regexp.MustCompile(`[.!?](\s|$)`)
+
+// CheckFeedback runs the leak check and makes sure free-text feedback looks
+// like the short plain prose the prompt asks for.
+func CheckFeedback(feedback, prompt string) error {
+	if err := CheckLeak(feedback, prompt); err != nil {
+		return err
+	}
+	trimmed := strings.TrimSpace(feedback)
+	switch {
+	case utf8.RuneCountInString(trimmed) > maxFeedbackChars:
+		return fmt.Errorf("%w: longer than %d characters", ErrFormat, maxFeedbackChars)
+	case len(sentenceEndRX.FindAllString(trimmed, -1)) > maxFeedbackSentences:
+		return fmt.Errorf("%w: more than %d sentences", ErrFormat, maxFeedbackSentences)
+	case strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") || strings.Contains(trimmed, "```"):
+		return fmt.Errorf("%w: not plain text", ErrFormat)
+	}
+	return nil
+}
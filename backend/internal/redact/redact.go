@@ -0,0 +1,162 @@
+// Package redact takes personal data out of student text before it's sent to
+// a model and puts it back into the reply afterwards.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindEmail        Kind = "email"
+	KindPersonnummer Kind = "personnummer"
+	KindPhone        Kind = "phone"
+	KindName         Kind = "name"
+)
+
+// A Match is one piece of personal data and the placeholder that replaced
+// it. The original value is unexported so it can't end up in a log by
+// accident.
+type Match struct {
+	Kind        Kind
+	Placeholder string
+	value       string
+}
+
+type detector struct {
+	kind  Kind
+	rx    *regexp.Regexp
+	group int
+	valid func(string) bool
+}
+
+var detectors = []detector{
+	{kind: KindEmail, rx: regexp.MustCompile(`[\p{L}\p{N}._%+-]+@[\p{L}\p{N}-]+(\.[\p{L}\p{N}-]+)+`)},
+	{kind: KindPersonnummer, rx: regexp.MustCompile(`\b(\d{2})?(\d{6})[-+]?(\d{4})\b`), valid: validPersonnummer},
+	{kind: KindPhone, rx: regexp.MustCompile(`(\+\d{1,3}|\b0)[ -]?\d{1,4}([ -]?\d{2,4}){1,4}\b`), valid: validPhone},
+	{kind: KindName, rx: regexp.MustCompile(`(?:(?i:jag heter|mitt namn är|my name is|i'm called|mvh|hälsningar|regards),?\s+)(\p{Lu}[\p{Ll}-]+(?:\s+\p{Lu}[\p{Ll}-]+)?)`), group: 1},
+}
+
+var placeholderRX = regexp.MustCompile(`\[(EMAIL|PERSONNUMMER|PHONE|NAME)_\d+\]`)
+
+// A Redactor keeps its placeholders stable across calls, so several answers
+// redacted with the same Redactor share one numbering and the same value
+// always gets the same placeholder.
+type Redactor struct {
+	matches []Match
+	counts  map[Kind]int
+}
+
+func New() *Redactor {
+	return &Redactor{counts: make(map[Kind]int)}
+}
+
+func (r *Redactor) Matches() []Match {
+	return r.matches
+}
+
+func (r *Redactor) placeholder(kind Kind, value string) string {
+	for _, m := range r.matches {
+		if m.Kind == kind && m.value == value {
+			return m.Placeholder
+		}
+	}
+	r.counts[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", strings.ToUpper(string(kind)), r.counts[kind])
+	r.matches = append(r.matches, Match{Kind: kind, Placeholder: placeholder, value: value})
+	return placeholder
+}
+
+// Redact replaces the personal data it finds with placeholders. Names are
+// only recognised where the text introduces or signs with them, so once a
+// name is known every later mention of it is replaced too.
+func (r *Redactor) Redact(text string) string {
+	for _, d := range detectors {
+		var b strings.Builder
+		last := 0
+		for _, loc := range d.rx.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[2*d.group], loc[2*d.group+1]
+			value := text[start:end]
+			if d.valid != nil && !d.valid(value) {
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(r.placeholder(d.kind, value))
+			last = end
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	for _, m := range r.matches {
+		if m.Kind == KindName {
+			rx := regexp.MustCompile(`(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(m.value) + `($|[^\p{L}\p{N}])`)
+			text = rx.ReplaceAllString(text, "${1}"+m.Placeholder+"${2}")
+		}
+	}
+	return text
+}
+
+// Restore puts the original values back in place of the placeholders.
+func (r *Redactor) Restore(text string) string {
+	return placeholderRX.ReplaceAllStringFunc(text, func(placeholder string) string {
+		i := slices.IndexFunc(r.matches, func(m Match) bool { return m.Placeholder == placeholder })
+		if i < 0 {
+			return placeholder
+		}
+		return r.matches[i].value
+	})
+}
+
+// ReplacePlaceholders swaps every placeholder in text for replacement, for
+// output that must stay anonymous.
+func ReplacePlaceholders(text, replacement string) string {
+	return placeholderRX.ReplaceAllLiteralString(text, replacement)
+}
+
+func digits(s string) []int {
+	var ds []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			ds = append(ds, int(r-'0'))
+		}
+	}
+	return ds
+}
+
+// validPersonnummer checks the date and the Luhn check digit of a Swedish
+// personal identity number, written with or without the century. Days 61-91
+// are coordination numbers.
+func validPersonnummer(s string) bool {
+	ds := digits(s)
+	if len(ds) == 12 {
+		ds = ds[2:]
+	}
+	if len(ds) != 10 {
+		return false
+	}
+	month := ds[2]*10 + ds[3]
+	day := ds[4]*10 + ds[5]
+	if month < 1 || month > 12 || !(day >= 1 && day <= 31 || day >= 61 && day <= 91) {
+		return false
+	}
+	sum := 0
+	for i, d := range ds {
+		if i%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func validPhone(s string) bool {
+	n := len(digits(s))
+	return n >= 8 && n <= 13
+}
@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		want  string
+		kinds []Kind
+	}{
+		{
+			name:  "E-mail",
+			text:  "Mejla mig på anna.svensson@skola.se om du undrar.",
+			want:  "Mejla mig på [EMAIL_1] om du undrar.",
+			kinds: []Kind{KindEmail},
+		},
+		{
+			name:  "Personnummer",
+			text:  "Mitt personnummer är 811218-9876 och min brors 19811218-9876.",
+			want:  "Mitt personnummer är [PERSONNUMMER_1] och min brors [PERSONNUMMER_2].",
+			kinds: []Kind{KindPersonnummer, KindPersonnummer},
+		},
+		{
+			name: "Personnummer with a bad check digit",
+			text: "Numret 811218-9877 är inte giltigt.",
+			want: "Numret 811218-9877 är inte giltigt.",
+		},
+		{
+			name:  "Phone numbers",
+			text:  "Ring 070-123 45 67 eller +46 8 123 456 78.",
+			want:  "Ring [PHONE_1] eller [PHONE_2].",
+			kinds: []Kind{KindPhone, KindPhone},
+		},
+		{
+			name: "Dates and years",
+			text: "Artikeln publicerades 2024-03-01, inte 1990.",
+			want: "Artikeln publicerades 2024-03-01, inte 1990.",
+		},
+		{
+			name:  "Introduced and repeated name",
+			text:  "Jag heter Åsa Lind. Källan är SVT.\nMvh Åsa",
+			want:  "Jag heter [NAME_1]. Källan är SVT.\nMvh [NAME_2]",
+			kinds: []Kind{KindName, KindName},
+		},
+		{
+			name:  "Same value twice",
+			text:  "a@b.se, a@b.se",
+			want:  "[EMAIL_1], [EMAIL_1]",
+			kinds: []Kind{KindEmail},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New()
+			assert.Equal(t, r.Redact(tt.text), tt.want)
+			assert.Equal(t, len(r.Matches()), len(tt.kinds))
+			for i, kind := range tt.kinds {
+				assert.Equal(t, r.Matches()[i].Kind, kind)
+			}
+			assert.Equal(t, r.Restore(r.Redact(tt.text)), tt.text)
+		})
+	}
+}
+
+func TestRedactorSharesPlaceholders(t *testing.T) {
+	r := New()
+	first := r.Redact("Jag heter Karin.")
+	second := r.Redact("Bra svar, Karin! Ring 0701234567.")
+	assert.Equal(t, first, "Jag heter [NAME_1].")
+	assert.Equal(t, second, "Bra svar, [NAME_1]! Ring [PHONE_1].")
+	assert.Equal(t, r.Restore("Tack [NAME_1], [NAME_9]"), "Tack Karin, [NAME_9]")
+	assert.Equal(t, ReplacePlaceholders(second, "[…]"), "Bra svar, […]! Ring […].")
+}
+
+func TestValidPersonnummer(t *testing.T) {
+	for s, want := range map[string]bool{
+		"8112189876":    true,
+		"19811218-9876": true,
+		"811278-9875":   false,
+		"811318-9876":   false,
+		"12345":         false,
+	} {
+		assert.Equal(t, validPersonnummer(s), want)
+	}
+}
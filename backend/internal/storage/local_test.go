@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/berberapan/info-eval/internal/assert"
+)
+
+func TestLocal(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	assert.NilError(t, err)
+	ctx := context.Background()
+
+	content := "källkritik"
+	sum := sha256.Sum256([]byte(content))
+	key := hex.EncodeToString(sum[:])
+
+	err = store.Put(ctx, key, strings.NewReader(content))
+	assert.NilError(t, err)
+
+	rc, err := store.Open(ctx, key)
+	assert.NilError(t, err)
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	assert.NilError(t, err)
+	assert.Equal(t, string(got), content)
+
+	err = store.Delete(ctx, key)
+	assert.NilError(t, err)
+
+	_, err = store.Open(ctx, key)
+	assert.Equal(t, errors.Is(err, ErrNotFound), true)
+
+	err = store.Delete(ctx, key)
+	assert.Equal(t, errors.Is(err, ErrNotFound), true)
+}
+
+func TestLocalInvalidKey(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	assert.NilError(t, err)
+
+	tests := []string{"", "../../etc/passwd", "ABC", strings.Repeat("g", 64)}
+	for _, key := range tests {
+		t.Run(key, func(t *testing.T) {
+			err := store.Put(context.Background(), key, strings.NewReader("x"))
+			assert.Equal(t, errors.Is(err, ErrInvalidKey), true)
+			_, err = store.Open(context.Background(), key)
+			assert.Equal(t, errors.Is(err, ErrInvalidKey), true)
+		})
+	}
+}
@@ -0,0 +1,3 @@
+ALTER TABLE exercise_question_options DROP COLUMN IF EXISTS "order";
+
+ALTER TABLE exercise_questions DROP COLUMN IF EXISTS "order";
@@ -0,0 +1,3 @@
+ALTER TABLE exercise_questions ADD COLUMN IF NOT EXISTS "order" INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE exercise_question_options ADD COLUMN IF NOT EXISTS "order" INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE scenarios DROP COLUMN IF EXISTS version;
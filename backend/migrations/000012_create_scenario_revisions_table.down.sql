@@ -0,0 +1,3 @@
+ALTER TABLE scenario_sessions DROP COLUMN IF EXISTS scenario_revision_id;
+
+DROP TABLE IF EXISTS scenario_revisions;
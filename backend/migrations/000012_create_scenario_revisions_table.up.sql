@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS scenario_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scenario_id UUID NOT NULL REFERENCES scenarios(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content JSONB NOT NULL,
+    content_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (scenario_id, revision)
+);
+
+ALTER TABLE scenario_sessions ADD COLUMN IF NOT EXISTS scenario_revision_id UUID REFERENCES scenario_revisions(id);
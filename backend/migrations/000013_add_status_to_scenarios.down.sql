@@ -0,0 +1,3 @@
+ALTER TABLE scenarios DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS scenario_status;
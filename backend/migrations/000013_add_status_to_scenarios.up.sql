@@ -0,0 +1,5 @@
+CREATE TYPE scenario_status AS ENUM ('draft', 'published', 'archived');
+
+ALTER TABLE scenarios ADD COLUMN IF NOT EXISTS status scenario_status NOT NULL DEFAULT 'published';
+
+ALTER TABLE scenarios ALTER COLUMN status SET DEFAULT 'draft';
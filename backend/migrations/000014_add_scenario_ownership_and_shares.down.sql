@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS scenario_shares;
+
+DROP TYPE IF EXISTS share_permission;
+
+ALTER TABLE scenarios DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,11 @@
+ALTER TABLE scenarios ADD COLUMN IF NOT EXISTS owner_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TYPE share_permission AS ENUM ('view', 'edit');
+
+CREATE TABLE IF NOT EXISTS scenario_shares (
+    scenario_id UUID NOT NULL REFERENCES scenarios(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    permission share_permission NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (scenario_id, user_id)
+);
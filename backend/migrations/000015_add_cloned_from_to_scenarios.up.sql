@@ -0,0 +1 @@
+ALTER TABLE scenarios ADD COLUMN IF NOT EXISTS cloned_from UUID REFERENCES scenarios(id) ON DELETE SET NULL;
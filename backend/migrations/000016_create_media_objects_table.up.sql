@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS media_objects (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sha256 TEXT UNIQUE NOT NULL,
+    content_type TEXT NOT NULL,
+    media_type media_type NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,8 @@
+ALTER TABLE exercise_question_options DROP COLUMN IF EXISTS match_text;
+
+DELETE FROM exercise_questions WHERE type::text IN ('multi_select', 'ordering', 'matching', 'likert');
+
+ALTER TYPE exercise_type RENAME TO exercise_type_old;
+CREATE TYPE exercise_type AS ENUM ('free_text', 'true_false', 'multiple_choice');
+ALTER TABLE exercise_questions ALTER COLUMN type TYPE exercise_type USING type::text::exercise_type;
+DROP TYPE exercise_type_old;
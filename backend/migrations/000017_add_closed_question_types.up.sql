@@ -0,0 +1,6 @@
+ALTER TYPE exercise_type ADD VALUE IF NOT EXISTS 'multi_select';
+ALTER TYPE exercise_type ADD VALUE IF NOT EXISTS 'ordering';
+ALTER TYPE exercise_type ADD VALUE IF NOT EXISTS 'matching';
+ALTER TYPE exercise_type ADD VALUE IF NOT EXISTS 'likert';
+
+ALTER TABLE exercise_question_options ADD COLUMN IF NOT EXISTS match_text TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE session_responses DROP COLUMN IF EXISTS max_score;
+ALTER TABLE session_responses DROP COLUMN IF EXISTS score;
+ALTER TABLE session_responses DROP COLUMN IF EXISTS grading;
@@ -0,0 +1,3 @@
+ALTER TABLE session_responses ADD COLUMN IF NOT EXISTS grading JSONB;
+ALTER TABLE session_responses ADD COLUMN IF NOT EXISTS score DOUBLE PRECISION;
+ALTER TABLE session_responses ADD COLUMN IF NOT EXISTS max_score DOUBLE PRECISION;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS feedback_jobs;
+
+DROP TYPE IF EXISTS feedback_job_status;
@@ -0,0 +1,19 @@
+CREATE TYPE feedback_job_status AS ENUM ('pending', 'running', 'done', 'dead');
+
+CREATE TABLE IF NOT EXISTS feedback_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_response_id UUID NOT NULL REFERENCES session_responses(id) ON DELETE CASCADE,
+    status feedback_job_status NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS feedback_jobs_pending_idx ON feedback_jobs (run_at) WHERE status = 'pending';
+
+INSERT INTO feedback_jobs (session_response_id)
+SELECT id FROM session_responses WHERE ai_feedback IS NULL;
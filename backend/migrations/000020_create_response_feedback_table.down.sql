@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS response_feedback;
+
+DROP TYPE IF EXISTS feedback_status;
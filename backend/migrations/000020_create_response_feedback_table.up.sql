@@ -0,0 +1,42 @@
+CREATE TYPE feedback_status AS ENUM ('pending', 'done', 'failed');
+
+CREATE TABLE IF NOT EXISTS response_feedback (
+    session_response_id UUID NOT NULL REFERENCES session_responses(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL,
+    status feedback_status NOT NULL DEFAULT 'pending',
+    feedback TEXT,
+    error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (session_response_id, question_id)
+);
+
+INSERT INTO response_feedback (session_response_id, question_id, status, feedback, error)
+SELECT sr.id, f.key::uuid,
+    CASE WHEN f.value LIKE 'Error:%' THEN 'failed'::feedback_status ELSE 'done'::feedback_status END,
+    CASE WHEN f.value LIKE 'Error:%' THEN NULL ELSE f.value END,
+    CASE WHEN f.value LIKE 'Error:%' THEN f.value ELSE NULL END
+FROM session_responses sr, jsonb_each_text(sr.ai_feedback) f
+WHERE f.key ~ '^[0-9a-f-]{36}$'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO response_feedback (session_response_id, question_id)
+SELECT sr.id, q.id
+FROM session_responses sr
+CROSS JOIN LATERAL jsonb_object_keys(CASE WHEN jsonb_typeof(sr.raw_answers) = 'object' THEN sr.raw_answers ELSE '{}'::jsonb END) k
+JOIN exercise_questions q ON q.id::text = k AND q."type" = 'free_text'
+WHERE sr.ai_feedback IS NULL
+ON CONFLICT DO NOTHING;
+
+DELETE FROM feedback_jobs j
+WHERE NOT EXISTS (
+    SELECT 1 FROM response_feedback rf
+    WHERE rf.session_response_id = j.session_response_id AND rf.status = 'pending'
+);
+
+UPDATE session_responses sr
+SET ai_feedback = (
+    SELECT jsonb_object_agg(f.key, f.value)
+    FROM jsonb_each(sr.ai_feedback) f
+    WHERE f.value::text NOT LIKE '"Error:%'
+)
+WHERE sr.ai_feedback IS NOT NULL;
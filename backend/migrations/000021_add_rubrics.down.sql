@@ -0,0 +1,3 @@
+ALTER TABLE response_feedback DROP COLUMN IF EXISTS assessment;
+
+ALTER TABLE exercise_questions DROP COLUMN IF EXISTS rubric;
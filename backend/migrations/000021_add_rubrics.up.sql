@@ -0,0 +1,3 @@
+ALTER TABLE exercise_questions ADD COLUMN IF NOT EXISTS rubric JSONB;
+
+ALTER TABLE response_feedback ADD COLUMN IF NOT EXISTS assessment JSONB;
@@ -0,0 +1,3 @@
+ALTER TABLE scenario_sessions DROP COLUMN IF EXISTS language;
+
+ALTER TABLE scenarios DROP COLUMN IF EXISTS language;
@@ -0,0 +1,8 @@
+ALTER TABLE scenarios ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'sv';
+
+ALTER TABLE scenario_sessions ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'sv';
+
+UPDATE scenario_sessions ss
+SET language = s.language
+FROM scenarios s
+WHERE s.id = ss.scenario_id;
@@ -0,0 +1,5 @@
+ALTER TABLE response_feedback DROP COLUMN IF EXISTS prompt_template_id;
+
+DROP TABLE IF EXISTS prompt_templates;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,11 @@
+ALTER TABLE session_responses DROP COLUMN IF EXISTS feedback_released_at;
+
+ALTER TABLE scenario_sessions DROP COLUMN IF EXISTS hold_feedback;
+
+ALTER TABLE response_feedback
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS teacher_feedback,
+    DROP COLUMN IF EXISTS review_status;
+
+DROP TYPE IF EXISTS feedback_review_status;
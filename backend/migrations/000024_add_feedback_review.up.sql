@@ -0,0 +1,11 @@
+CREATE TYPE feedback_review_status AS ENUM ('unreviewed', 'approved', 'edited', 'replaced');
+
+ALTER TABLE response_feedback
+    ADD COLUMN IF NOT EXISTS review_status feedback_review_status NOT NULL DEFAULT 'unreviewed',
+    ADD COLUMN IF NOT EXISTS teacher_feedback TEXT,
+    ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ;
+
+ALTER TABLE scenario_sessions ADD COLUMN IF NOT EXISTS hold_feedback BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE session_responses ADD COLUMN IF NOT EXISTS feedback_released_at TIMESTAMPTZ;
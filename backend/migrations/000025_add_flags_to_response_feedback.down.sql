@@ -0,0 +1 @@
+ALTER TABLE response_feedback DROP COLUMN IF EXISTS flags;
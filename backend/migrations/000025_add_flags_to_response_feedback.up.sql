@@ -0,0 +1 @@
+ALTER TABLE response_feedback ADD COLUMN IF NOT EXISTS flags TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS feedback_cache_stats;
+DROP TABLE IF EXISTS feedback_cache;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS feedback_cache (
+    key TEXT PRIMARY KEY,
+    feedback TEXT NOT NULL,
+    assessment JSONB,
+    prompt_template_id UUID REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    model TEXT NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS feedback_cache_expires_at_idx ON feedback_cache (expires_at);
+
+CREATE TABLE IF NOT EXISTS feedback_cache_stats (
+    day DATE PRIMARY KEY DEFAULT CURRENT_DATE,
+    hits INTEGER NOT NULL DEFAULT 0,
+    misses INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,2 @@
+ALTER TABLE session_responses DROP COLUMN IF EXISTS summary_prompt_template_id;
+ALTER TABLE session_responses DROP COLUMN IF EXISTS ai_summary;
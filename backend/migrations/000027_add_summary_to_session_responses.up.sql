@@ -0,0 +1,2 @@
+ALTER TABLE session_responses ADD COLUMN IF NOT EXISTS ai_summary JSONB;
+ALTER TABLE session_responses ADD COLUMN IF NOT EXISTS summary_prompt_template_id UUID REFERENCES prompt_templates(id) ON DELETE SET NULL;
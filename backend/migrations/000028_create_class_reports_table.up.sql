@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS class_reports (
+    scenario_session_id UUID NOT NULL REFERENCES scenario_sessions(id) ON DELETE CASCADE,
+    question_id UUID NOT NULL,
+    report JSONB NOT NULL,
+    answer_count INTEGER NOT NULL,
+    last_submitted_at TIMESTAMPTZ NOT NULL,
+    prompt_template_id UUID REFERENCES prompt_templates(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (scenario_session_id, question_id)
+);
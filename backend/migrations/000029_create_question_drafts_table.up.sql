@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS question_drafts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    exercise_id UUID NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    draft JSONB NOT NULL,
+    prompt_template_id UUID REFERENCES prompt_templates(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS question_drafts_exercise_id_idx ON question_drafts (exercise_id);
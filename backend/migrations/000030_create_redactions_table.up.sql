@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS redactions (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    purpose TEXT NOT NULL,
+    session_response_id UUID REFERENCES session_responses(id) ON DELETE SET NULL,
+    scenario_session_id UUID REFERENCES scenario_sessions(id) ON DELETE SET NULL,
+    question_id UUID,
+    provider TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS redactions_created_at_idx ON redactions(created_at);
@@ -0,0 +1 @@
+-- Deleted cache entries are regenerated on demand; nothing to restore.
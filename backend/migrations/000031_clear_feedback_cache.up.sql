@@ -0,0 +1,3 @@
+-- Entries written before the cache switched to redacted answers may hold
+-- personal data. They are only an optimisation, so drop them all.
+DELETE FROM feedback_cache;
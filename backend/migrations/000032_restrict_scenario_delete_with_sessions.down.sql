@@ -0,0 +1,3 @@
+ALTER TABLE scenario_sessions DROP CONSTRAINT IF EXISTS scenario_sessions_scenario_id_fkey;
+ALTER TABLE scenario_sessions ADD CONSTRAINT scenario_sessions_scenario_id_fkey
+    FOREIGN KEY (scenario_id) REFERENCES scenarios(id) ON DELETE CASCADE;
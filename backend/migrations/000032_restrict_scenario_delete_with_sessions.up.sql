@@ -0,0 +1,5 @@
+-- Sessions hold students' work, so a scenario that has any can only be
+-- archived, not deleted.
+ALTER TABLE scenario_sessions DROP CONSTRAINT IF EXISTS scenario_sessions_scenario_id_fkey;
+ALTER TABLE scenario_sessions ADD CONSTRAINT scenario_sessions_scenario_id_fkey
+    FOREIGN KEY (scenario_id) REFERENCES scenarios(id) ON DELETE RESTRICT;